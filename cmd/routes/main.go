@@ -0,0 +1,62 @@
+//Command routes prints route table exposed by router debug handler
+// 	w.HandleGet("/_debug/routes", w.RouteListHandler())
+// 	go run github.com/enorith/http/cmd/routes -url http://localhost:8000/_debug/routes
+//
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/enorith/http/router"
+)
+
+func main() {
+	url := flag.String("url", "http://localhost:8000/_debug/routes", "url of route list debug handler")
+	format := flag.String("format", "text", "output format, text or json")
+	flag.Parse()
+
+	infos, e := fetch(*url)
+	if e != nil {
+		fmt.Fprintln(os.Stderr, e)
+		os.Exit(1)
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		e = enc.Encode(infos)
+	case "text":
+		e = router.WriteRouteTable(os.Stdout, infos)
+	default:
+		e = fmt.Errorf("unsupported format [%s]", *format)
+	}
+
+	if e != nil {
+		fmt.Fprintln(os.Stderr, e)
+		os.Exit(1)
+	}
+}
+
+func fetch(url string) ([]router.RouteInfo, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, e := client.Get(url)
+	if e != nil {
+		return nil, e
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch routes from %s: %s", url, resp.Status)
+	}
+
+	var infos []router.RouteInfo
+	e = json.NewDecoder(resp.Body).Decode(&infos)
+
+	return infos, e
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"time"
+)
+
+//ServerConfig of server, covers both fasthttp and net/http backends.
+// Zero values fallback to package defaults and limits of kernel
+// 	var config http.ServerConfig
+// 	yaml.Unmarshal(data, &config) // read_timeout: 10s
+// 	s := http.NewServer(cr, debug).WithConfig(config)
+//
+type ServerConfig struct {
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout waiting for connections when context of Start done
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// MaxRequestBodySize in bytes, fallback to Kernel.MaxRequestBodySize
+	MaxRequestBodySize int `yaml:"max_request_body_size"`
+	// MaxHeaderBytes of request headers, ReadBufferSize limits headers of fasthttp
+	MaxHeaderBytes int `yaml:"max_header_bytes"`
+	// TCPKeepalive fallback to Kernel.IsKeepAlive
+	TCPKeepalive bool `yaml:"tcp_keepalive"`
+
+	// H2C serve HTTP/2 without TLS, net/http only
+	H2C bool `yaml:"h2c"`
+
+	// fasthttp only
+
+	// Concurrency fallback to Kernel.RequestCurrency
+	Concurrency        int  `yaml:"concurrency"`
+	ReadBufferSize     int  `yaml:"read_buffer_size"`
+	WriteBufferSize    int  `yaml:"write_buffer_size"`
+	MaxConnsPerIP      int  `yaml:"max_conns_per_ip"`
+	MaxRequestsPerConn int  `yaml:"max_requests_per_conn"`
+	ReduceMemoryUsage  bool `yaml:"reduce_memory_usage"`
+}
+
+//DefaultServerConfig config of package defaults
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		ReadTimeout:     ReadTimeout,
+		WriteTimeout:    WriteTimeout,
+		IdleTimeout:     IdleTimeout,
+		ShutdownTimeout: ShutdownTimeout,
+	}
+}
+
+//resolve zero values of config by defaults and kernel
+func (c ServerConfig) resolve(k *Kernel) ServerConfig {
+	d := DefaultServerConfig()
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = d.ReadTimeout
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = d.WriteTimeout
+	}
+	if c.IdleTimeout == 0 {
+		c.IdleTimeout = d.IdleTimeout
+	}
+	if c.ShutdownTimeout == 0 {
+		c.ShutdownTimeout = d.ShutdownTimeout
+	}
+	if c.MaxRequestBodySize == 0 {
+		c.MaxRequestBodySize = k.MaxRequestBodySize
+	}
+	if c.Concurrency == 0 {
+		c.Concurrency = k.RequestCurrency
+	}
+	c.TCPKeepalive = c.TCPKeepalive || k.IsKeepAlive()
+
+	return c
+}
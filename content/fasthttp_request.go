@@ -56,6 +56,10 @@ func (r FastHttpRequest) GetURL() *url.URL {
 	}
 }
 
+func (r *FastHttpRequest) Host() string {
+	return string(r.origin.Host())
+}
+
 func (r *FastHttpRequest) IsXmlHttpRequest() bool {
 
 	return bytes.Equal(r.origin.Request.Header.Peek("X-Requested-With"), []byte("XMLHttpRequest"))
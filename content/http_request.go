@@ -69,6 +69,10 @@ func (n *NetHttpRequest) GetURL() *url.URL {
 	return n.origin.URL
 }
 
+func (n *NetHttpRequest) Host() string {
+	return n.origin.Host
+}
+
 func (n *NetHttpRequest) Get(key string) []byte {
 	q := n.origin.URL.Query().Get(key)
 
@@ -78,6 +78,11 @@ type WithPathInfo interface {
 	GetUri() []byte
 }
 
+//WithHost request host, including port if present
+type WithHost interface {
+	Host() string
+}
+
 type WithRequestCookies interface {
 	CookieByte(key string) []byte
 }
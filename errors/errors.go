@@ -52,6 +52,16 @@ func (nf NotFound) StatusCode() int {
 	return 404
 }
 
+type MethodNotAllowed string
+
+func (m MethodNotAllowed) Error() string {
+	return string(m)
+}
+
+func (m MethodNotAllowed) StatusCode() int {
+	return 405
+}
+
 type StatusCode int
 
 func (c StatusCode) Error() string {
@@ -10,6 +10,8 @@ require (
 	github.com/enorith/supports v0.1.6
 	github.com/json-iterator/go v1.1.12
 	github.com/valyala/fasthttp v1.55.0
+	golang.org/x/net v0.35.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -20,4 +22,5 @@ require (
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/stretchr/testify v1.7.0 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
 )
@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/enorith/container"
@@ -52,6 +53,9 @@ type Kernel struct {
 	Handler            handlerType
 	cr                 ContainerRegister
 	resolver           RequestResolver
+
+	// builtin middleware resolved before groups, not replaced by SetMiddlewareGroup
+	builtin map[string]pipeline.RequestMiddleware
 }
 
 func (k *Kernel) Wrapper() *router.Wrapper {
@@ -89,21 +93,36 @@ func (k *Kernel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					http.SetCookie(w, c)
 				}
 			}
+			fp, isFile := resp.(*content.File)
+			writeBody := func(dst io.Writer) {
+				if tp, ok := resp.(contracts.TemplateResponseContract); ok {
+					temp := tp.Template()
+					temp.Execute(dst, tp.TemplateData())
+				} else if wp, ok := resp.(io.WriterTo); ok {
+					wp.WriteTo(dst)
+				} else {
+					body := resp.Content()
+					dst.Write(body)
+				}
+			}
+			// HEAD request: drop body, keep headers and content length
+			isHead := r.Method == http.MethodHead && !isFile && !resp.Handled()
+			if status := resp.StatusCode(); isHead && status != http.StatusNoContent &&
+				status != http.StatusNotModified && w.Header().Get("Content-Length") == "" {
+				var cw countWriter
+				writeBody(&cw)
+				w.Header().Set("Content-Length", strconv.FormatInt(int64(cw), 10))
+			}
+
 			if !resp.Handled() {
 				// call after set headers, before write body
 				w.WriteHeader(resp.StatusCode())
 			}
 
-			if tp, ok := resp.(contracts.TemplateResponseContract); ok {
-				temp := tp.Template()
-				temp.Execute(w, tp.TemplateData())
-			} else if fp, ok := resp.(*content.File); ok {
+			if isFile {
 				http.ServeFile(w, r, fp.Path())
-			} else if wp, ok := resp.(io.WriterTo); ok {
-				wp.WriteTo(w)
-			} else {
-				body := resp.Content()
-				w.Write(body)
+			} else if !isHead {
+				writeBody(w)
 			}
 			code = resp.StatusCode()
 		}
@@ -116,6 +135,10 @@ func (k *Kernel) FastHttpHandler(ctx *fasthttp.RequestCtx) {
 	k.handleFunc(func() (request contracts.RequestContract, code int) {
 		request = content.NewFastHttpRequest(ctx)
 		resp := k.Handle(request)
+		if ctx.IsHead() {
+			// body is dropped, "Content-Length" is kept
+			ctx.Response.SkipBody = true
+		}
 
 		if k.tcpKeepAlive {
 			resp.SetHeader("Connection", "keep-alive")
@@ -124,7 +147,9 @@ func (k *Kernel) FastHttpHandler(ctx *fasthttp.RequestCtx) {
 			ctx.Redirect(rr.URL(), rr.StatusCode())
 		}
 
-		ctx.Response.SetStatusCode(resp.StatusCode())
+		if !resp.Handled() {
+			ctx.Response.SetStatusCode(resp.StatusCode())
+		}
 		if resp.Headers() != nil {
 			for k, v := range resp.Headers() {
 				ctx.Response.Header.Set(k, v)
@@ -141,6 +166,11 @@ func (k *Kernel) FastHttpHandler(ctx *fasthttp.RequestCtx) {
 			h(ctx)
 			return
 		}
+		if resp.Handled() {
+			// written by handler, eg: mounted fasthttp handler
+			code = ctx.Response.StatusCode()
+			return
+		}
 
 		if tp, ok := resp.(contracts.TemplateResponseContract); ok {
 			temp := tp.Template()
@@ -165,6 +195,13 @@ func (k *Kernel) FastHttpHandler(ctx *fasthttp.RequestCtx) {
 	})
 }
 
+type countWriter int64
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	*c += countWriter(len(p))
+	return len(p), nil
+}
+
 func (k *Kernel) SetMiddlewareGroup(middlewareGroup map[string][]pipeline.RequestMiddleware) {
 	k.middlewareGroup = middlewareGroup
 }
@@ -235,9 +272,14 @@ func (k *Kernel) SendRequestToRouter(r contracts.RequestContract) contracts.Resp
 
 	ioc := r.GetContainer()
 	ioc.Bind(&router.ParamRoute{}, p, true)
+	ioc.Bind(&router.Wrapper{}, k.wrapper, true)
+	k.wrapper.BindModels(r)
 
 	mid := p.Middleware()
 	for _, v := range mid {
+		if md, exists := k.builtin[v]; exists {
+			pipe.ThroughMiddleware(md)
+		}
 		if ms, exists := k.middlewareGroup[v]; exists {
 			for _, md := range ms {
 				pipe.ThroughMiddleware(md)
@@ -269,6 +311,9 @@ func NewKernel(cr ContainerRegister, debug bool) *Kernel {
 	}
 	k.RequestCurrency = DefaultConcurrency
 	k.middleware = []pipeline.RequestMiddleware{}
-	k.middlewareGroup = make(map[string][]pipeline.RequestMiddleware)
+	k.middlewareGroup = map[string][]pipeline.RequestMiddleware{}
+	k.builtin = map[string]pipeline.RequestMiddleware{
+		"signed": router.SignedMiddleware(k.wrapper),
+	}
 	return k
 }
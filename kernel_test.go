@@ -1,14 +1,39 @@
 package http_test
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/enorith/container"
 	"github.com/enorith/http"
 	"github.com/enorith/http/content"
 	"github.com/enorith/http/contracts"
 	"github.com/enorith/http/pipeline"
+	"github.com/enorith/http/router"
 	"github.com/enorith/http/tests"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/http2"
+	"gopkg.in/yaml.v3"
 )
 
 var k *http.Kernel
@@ -60,6 +85,176 @@ func TestKernel_Middleware2(t *testing.T) {
 	t.Log("handle mid2 result", resp.StatusCode(), string(resp.Content()), resp.Headers())
 }
 
+func TestKernel_ServeHTTPHead(t *testing.T) {
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, httptest.NewRequest("HEAD", "/hello", nil))
+
+	if w.Code != 200 || w.Body.Len() != 0 || w.Header().Get("Content-Length") != "2" {
+		t.Fatalf("unexpected HEAD response %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+func TestKernel_FastHttpHead(t *testing.T) {
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod("HEAD")
+	ctx.Request.SetRequestURI("/hello")
+	k.FastHttpHandler(&ctx)
+
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	ctx.Response.Write(bw)
+	bw.Flush()
+
+	raw := buf.String()
+	if ctx.Response.StatusCode() != 200 || ctx.Response.Header.ContentLength() != 2 ||
+		!strings.HasSuffix(raw, "\r\n\r\n") {
+		t.Fatalf("unexpected HEAD response %q", raw)
+	}
+}
+
+func TestKernel_Resource(t *testing.T) {
+	resp := k.Handle(tests.NewRequest("GET", "/articles/42"))
+
+	if resp.StatusCode() != 200 || string(resp.Content()) != "article 42" {
+		t.Fatalf("unexpected resource response %d %s", resp.StatusCode(), resp.Content())
+	}
+}
+
+func TestKernel_Mount(t *testing.T) {
+	resp := k.Handle(tests.NewRequest("GET", "/tenants/acme/admin/users/42"))
+	if string(resp.Content()) != "acme 42" || resp.Headers()["X-Middleware"] != "demo" {
+		t.Fatalf("unexpected mounted wrapper response %d %s %v", resp.StatusCode(), resp.Content(), resp.Headers())
+	}
+
+	w := httptest.NewRecorder()
+	k.ServeHTTP(w, httptest.NewRequest("GET", "/std/tenants/acme/path?q=1", nil))
+	if w.Body.String() != "/path acme" {
+		t.Fatalf("unexpected mounted http.Handler response %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	k.ServeHTTP(w, httptest.NewRequest("POST", "/fast/ping", nil))
+	if w.Code != 201 || w.Body.String() != "POST /ping" {
+		t.Fatalf("unexpected mounted fasthttp handler response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestKernel_Model(t *testing.T) {
+	resp := k.Handle(tests.NewRequest("GET", "/users/1"))
+	if resp.StatusCode() != 200 || string(resp.Content()) != "enorith" {
+		t.Fatalf("unexpected model response %d %s", resp.StatusCode(), resp.Content())
+	}
+
+	if resp = k.Handle(tests.NewRequest("GET", "/users/2")); resp.StatusCode() != 404 {
+		t.Fatalf("missing model should response 404, %d giving", resp.StatusCode())
+	}
+}
+
+func TestKernel_Signed(t *testing.T) {
+	w := k.Wrapper()
+	u, e := w.SignedURL("unsubscribe", map[string]interface{}{"user": 42}, time.Hour)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if resp := k.Handle(tests.NewRequest("GET", u)); resp.StatusCode() != 200 {
+		t.Fatalf("signed url %s should pass, %d giving", u, resp.StatusCode())
+	}
+	if resp := k.Handle(tests.NewRequest("GET", strings.Replace(u, "42", "43", 1))); resp.StatusCode() != 403 {
+		t.Fatalf("tampered url should response 403, %d giving", resp.StatusCode())
+	}
+
+	expired, _ := router.NewSigner([]byte("old-secret")).Sign("/unsubscribe/42", time.Now().Add(-time.Minute))
+	if resp := k.Handle(tests.NewRequest("GET", expired)); resp.StatusCode() != 403 {
+		t.Fatalf("expired url should response 403, %d giving", resp.StatusCode())
+	}
+	rotated, _ := router.NewSigner([]byte("old-secret")).Sign("/unsubscribe/42", time.Time{})
+	if resp := k.Handle(tests.NewRequest("GET", rotated)); resp.StatusCode() != 200 {
+		t.Fatalf("url signed by rotated key should pass, %d giving", resp.StatusCode())
+	}
+}
+
+func TestKernel_SignedMiddlewareGroup(t *testing.T) {
+	sk := http.NewKernel(func(request contracts.RequestContract) container.Interface {
+		return container.New()
+	}, false)
+	var dm DemoMiddleware
+	sk.SetMiddlewareGroup(map[string][]pipeline.RequestMiddleware{"web": {dm}})
+	w := sk.Wrapper()
+	w.SigningKeys([]byte("secret"))
+	w.Get("/dl/:id", func() string { return "file" }).Name("dl").Middleware("signed")
+
+	if resp := sk.Handle(tests.NewRequest("GET", "/dl/1")); resp.StatusCode() != 403 {
+		t.Fatalf("unsigned url should response 403 after groups replaced, %d giving", resp.StatusCode())
+	}
+	u, _ := w.SignedURL("dl", map[string]interface{}{"id": 1}, time.Hour)
+	if resp := sk.Handle(tests.NewRequest("GET", u)); resp.StatusCode() != 200 {
+		t.Fatalf("signed url %s should pass, %d giving", u, resp.StatusCode())
+	}
+}
+
+func TestKernel_SigningKeysRotation(t *testing.T) {
+	sk := http.NewKernel(func(request contracts.RequestContract) container.Interface {
+		return container.New()
+	}, false)
+	w := sk.Wrapper()
+	w.SigningKeys([]byte("secret"))
+	w.Get("/dl/:id", func() string { return "file" }).Name("dl").Middleware("signed")
+	u, _ := w.SignedURL("dl", map[string]interface{}{"id": 1}, time.Hour)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			// keys rotated while serving
+			w.SigningKeys([]byte("new-secret"), []byte("secret"))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if resp := sk.Handle(tests.NewRequest("GET", u)); resp.StatusCode() != 200 {
+			t.Fatalf("url signed by rotated key should pass, %d giving", resp.StatusCode())
+		}
+	}
+	<-done
+}
+
+func TestKernel_SignedDomain(t *testing.T) {
+	sk := http.NewKernel(func(request contracts.RequestContract) container.Interface {
+		return container.New()
+	}, false)
+	w := sk.Wrapper()
+	w.SigningKeys([]byte("secret"))
+	w.Domain("{tenant}.example.com", func(w *router.Wrapper) {
+		w.Get("/invites/:id", func() string { return "ok" }).Name("invite").Middleware("signed")
+	})
+
+	u, e := w.SignedURL("invite", map[string]interface{}{"tenant": "a", "id": 1}, time.Hour)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if resp := sk.Handle(tests.NewRequest("GET", u)); resp.StatusCode() != 200 {
+		t.Fatalf("signed url %s should pass, %d giving", u, resp.StatusCode())
+	}
+	other := strings.Replace(u, "//a.", "//b.", 1)
+	if resp := sk.Handle(tests.NewRequest("GET", other)); resp.StatusCode() != 403 {
+		t.Fatalf("url signed for another tenant should response 403, %d giving", resp.StatusCode())
+	}
+	path, _ := router.NewSigner([]byte("secret")).Sign("/invites/1", time.Time{})
+	if resp := sk.Handle(tests.NewRequest("GET", "//b.example.com"+path)); resp.StatusCode() != 403 {
+		t.Fatalf("url signed without host should response 403 for domain route, %d giving", resp.StatusCode())
+	}
+}
+
+type User struct {
+	ID   string
+	Name string
+}
+
+type ArticleController struct{}
+
+func (ArticleController) Show(id content.ParamInt64) string {
+	return fmt.Sprintf("article %d", id)
+}
+
 type CustomResp string
 
 func (c CustomResp) StatusCode() int {
@@ -99,6 +294,518 @@ func (d Demo2Middleware) Handle(r contracts.RequestContract, next pipeline.PipeH
 	return resp
 }
 
+func TestServer_Start(t *testing.T) {
+	for _, netHttp := range []bool{false, true} {
+		s := http.NewServer(func(request contracts.RequestContract) container.Interface {
+			return container.New()
+		}, false)
+		if netHttp {
+			s.Kernel().Handler = http.HandlerNetHttp
+		}
+		s.Kernel().Wrapper().Get("/ping", func() string { return "pong" })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errc := make(chan error, 1)
+		go func() {
+			errc <- s.Start(ctx, "127.0.0.1:0")
+		}()
+
+		select {
+		case <-s.Ready():
+		case e := <-errc:
+			t.Fatalf("start error: %v", e)
+		}
+		resp, e := nethttp.Get("http://" + s.Addr().String() + "/ping")
+		if e != nil {
+			t.Fatal(e)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "pong" {
+			t.Fatalf("unexpected body %q", body)
+		}
+
+		if netHttp {
+			cancel()
+		} else if e := s.Shutdown(context.Background()); e != nil {
+			t.Fatal(e)
+		}
+		if e := <-errc; e != nil {
+			t.Fatalf("start should return nil after shutdown, %v giving", e)
+		}
+		cancel()
+
+		if e := s.Start(context.Background(), "127.0.0.1:0"); e == nil {
+			t.Fatal("server should not start twice")
+		}
+	}
+
+	// ready is closed if server failed to start or shut down before
+	inUse, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer inUse.Close()
+	failed := restartServer("fasthttp", "")
+	go failed.Start(context.Background(), inUse.Addr().String())
+	closed := restartServer("net/http", "")
+	closed.Shutdown(context.Background())
+	go closed.Start(context.Background(), "127.0.0.1:0")
+	for _, s := range []*http.Server{failed, closed} {
+		select {
+		case <-s.Ready():
+		case <-time.After(5 * time.Second):
+			t.Fatal("ready should be closed if server not started")
+		}
+		if s.Addr() != nil {
+			t.Fatalf("addr should be nil if server not started, %s giving", s.Addr())
+		}
+	}
+}
+
+func TestServerConfig(t *testing.T) {
+	var config http.ServerConfig
+	e := yaml.Unmarshal([]byte("read_timeout: 3s\nmax_conns_per_ip: 8\nmax_request_body_size: 4\nreduce_memory_usage: true\n"), &config)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	s := http.NewServer(func(request contracts.RequestContract) container.Interface {
+		return container.New()
+	}, false).WithConfig(config)
+	other := http.NewServer(func(request contracts.RequestContract) container.Interface {
+		return container.New()
+	}, false)
+
+	fs := s.GetFastHttpServer(s.Kernel())
+	if fs.ReadTimeout != 3*time.Second || fs.MaxConnsPerIP != 8 || !fs.ReduceMemoryUsage || fs.WriteTimeout != http.WriteTimeout {
+		t.Fatalf("unexpected fasthttp server %v %d %v %v", fs.ReadTimeout, fs.MaxConnsPerIP, fs.ReduceMemoryUsage, fs.WriteTimeout)
+	}
+	if other.GetFastHttpServer(other.Kernel()).ReadTimeout != http.ReadTimeout {
+		t.Fatal("config should not be shared between servers")
+	}
+
+	s.Kernel().Wrapper().Post("/echo", func(r contracts.RequestContract) string {
+		return string(r.GetContent())
+	})
+	w := httptest.NewRecorder()
+	s.GetNetHttpServer(s.Kernel()).Handler.ServeHTTP(w, httptest.NewRequest("POST", "/echo", strings.NewReader("too large")))
+	if w.Body.String() == "too large" {
+		t.Fatal("request body should be limited")
+	}
+}
+
+func TestServer_StartTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := dir+"/server.crt", dir+"/server.key"
+	writeCert(t, certFile, keyFile, 1)
+	cr, e := http.NewCertReloader(certFile, keyFile)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	for _, netHttp := range []bool{false, true} {
+		s := http.NewServer(func(request contracts.RequestContract) container.Interface {
+			return container.New()
+		}, false)
+		if netHttp {
+			s.Kernel().Handler = http.HandlerNetHttp
+		}
+		s.Kernel().Wrapper().Get("/ping", func() string { return "pong" })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errc := make(chan error, 1)
+		go func() {
+			errc <- s.StartTLS(ctx, "127.0.0.1:0", &tls.Config{GetCertificate: cr.GetCertificate})
+		}()
+		select {
+		case <-s.Ready():
+		case e := <-errc:
+			t.Fatalf("start error: %v", e)
+		}
+
+		client := &nethttp.Client{Transport: &nethttp.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: true,
+		}}
+		resp, e := client.Get("https://" + s.Addr().String() + "/ping")
+		if e != nil {
+			t.Fatal(e)
+		}
+		resp.Body.Close()
+		if netHttp && resp.ProtoMajor != 2 {
+			t.Fatalf("net/http backend should serve HTTP/2, %s giving", resp.Proto)
+		}
+		if serial := resp.TLS.PeerCertificates[0].SerialNumber.Int64(); serial != 1 {
+			t.Fatalf("unexpected certificate serial %d", serial)
+		}
+
+		cancel()
+		if e := <-errc; e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	writeCert(t, certFile, keyFile, 2)
+	if e := cr.Reload(); e != nil {
+		t.Fatal(e)
+	}
+	if cert, _ := cr.GetCertificate(nil); mustParse(t, cert.Certificate[0]).SerialNumber.Int64() != 2 {
+		t.Fatal("reloaded certificate expected")
+	}
+}
+
+func TestServer_H2C(t *testing.T) {
+	s := http.NewServer(func(request contracts.RequestContract) container.Interface {
+		return container.New()
+	}, false).WithConfig(http.ServerConfig{H2C: true})
+	s.Kernel().Handler = http.HandlerNetHttp
+	s.Kernel().Wrapper().Get("/ping", func() string { return "pong" })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Start(ctx, "127.0.0.1:0")
+	<-s.Ready()
+
+	client := &nethttp.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	resp, e := client.Get("http://" + s.Addr().String() + "/ping")
+	if e != nil {
+		t.Fatal(e)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("h2c expected, %s giving", resp.Proto)
+	}
+}
+
+func TestServer_StartBindings(t *testing.T) {
+	cr := func(request contracts.RequestContract) container.Interface {
+		return container.New()
+	}
+	s := http.NewServer(cr, false)
+	s.Kernel().Wrapper().Get("/ping", func() string { return "pong" })
+	admin := http.NewKernel(cr, false)
+	admin.Handler = http.HandlerNetHttp
+	admin.Wrapper().Get("/ping", func() string { return "admin" })
+
+	inherited, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	f, _ := inherited.(*net.TCPListener).File()
+	defer f.Close()
+	defer inherited.Close()
+	// inherited descriptor is owned by server
+	fd, e := syscall.Dup(int(f.Fd()))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	sock := t.TempDir() + "/app.sock"
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.StartBindings(ctx,
+			http.Binding{Addr: "unix:" + sock},
+			http.Binding{Addr: "127.0.0.1:0", Kernel: admin},
+			http.Binding{Addr: fmt.Sprintf("fd:%d", fd)},
+		)
+	}()
+	select {
+	case <-s.Ready():
+	case e := <-errc:
+		t.Fatal(e)
+	}
+
+	unixClient := &nethttp.Client{Transport: &nethttp.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", sock)
+		},
+	}}
+	addrs := s.Addrs()
+	cases := []struct {
+		client *nethttp.Client
+		url    string
+		body   string
+	}{
+		{unixClient, "http://app/ping", "pong"},
+		{nethttp.DefaultClient, "http://" + addrs[1].String() + "/ping", "admin"},
+		{nethttp.DefaultClient, "http://" + addrs[2].String() + "/ping", "pong"},
+	}
+	for _, c := range cases {
+		resp, e := c.client.Get(c.url)
+		if e != nil {
+			t.Fatal(e)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != c.body {
+			t.Fatalf("[%s] expect %s, %s giving", c.url, c.body, body)
+		}
+	}
+
+	cancel()
+	if e := <-errc; e != nil {
+		t.Fatal(e)
+	}
+	if _, e := os.Stat(sock); !os.IsNotExist(e) {
+		t.Fatal("socket file should be removed")
+	}
+}
+
+func TestListen_UnixSocket(t *testing.T) {
+	sock := t.TempDir() + "/app.sock"
+	ln, e := http.Listen("unix:" + sock)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e := http.Listen("unix:" + sock); !errors.Is(e, syscall.EADDRINUSE) {
+		t.Fatalf("socket in use should not be taken over, %v giving", e)
+	}
+	if c, e := net.Dial("unix", sock); e != nil {
+		t.Fatalf("socket in use should be kept, %v giving", e)
+	} else {
+		c.Close()
+	}
+
+	// stale socket file of exited process
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+	ln, e = http.Listen("unix:" + sock)
+	if e != nil {
+		t.Fatalf("stale socket should be removed, %v giving", e)
+	}
+	ln.Close()
+}
+
+func TestServer_ProxyProtocol(t *testing.T) {
+	v2 := append([]byte("\r\n\r\n\x00\r\nQUIT\n\x21\x11\x00\x0c"),
+		203, 0, 113, 7, 127, 0, 0, 1, 0x13, 0x88, 0, 80)
+	for _, backend := range []string{"fasthttp", "net/http"} {
+		s := restartServer(backend, "")
+		// remote address is taken on accepting
+		s.Config.MaxConnsPerIP = 100
+		s.Kernel().Wrapper().Get("/ip", func(r contracts.RequestContract) string {
+			return r.GetClientIp() + "|" + r.RemoteAddr()
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		errc := make(chan error, 1)
+		go func() {
+			errc <- s.StartBindings(ctx,
+				http.Binding{Addr: "127.0.0.1:0", ProxyProtocol: true, TrustedProxies: []string{"127.0.0.1"}},
+				http.Binding{Addr: "127.0.0.1:0", ProxyProtocol: true, TrustedProxies: []string{"10.0.0.0/8"}},
+				http.Binding{Addr: "127.0.0.1:0", ProxyProtocol: true, TrustedProxies: []string{"*"}},
+			)
+		}()
+		select {
+		case <-s.Ready():
+		case e := <-errc:
+			t.Fatal(e)
+		}
+
+		addrs := s.Addrs()
+		cases := []struct {
+			addr   net.Addr
+			header []byte
+			ip     string
+		}{
+			{addrs[0], []byte("PROXY TCP4 192.0.2.1 127.0.0.1 56324 80\r\n"), "192.0.2.1"},
+			{addrs[0], []byte("PROXY TCP6 2001:db8::1 ::1 56324 80\r\n"), "2001:db8::1"},
+			{addrs[0], []byte("PROXY UNKNOWN\r\n"), "127.0.0.1"},
+			{addrs[0], v2, "203.0.113.7"},
+			// header is required from trusted sources
+			{addrs[0], nil, ""},
+			{addrs[0], []byte("PROXY TCP4 192.0.2.1\r\n"), ""},
+			// untrusted source is served as it is
+			{addrs[1], nil, "127.0.0.1"},
+			{addrs[2], []byte("PROXY TCP4 192.0.2.2 127.0.0.1 56324 80\r\n"), "192.0.2.2"},
+		}
+		// idle connection without header does not block others
+		idle, e := net.Dial("tcp", addrs[0].String())
+		if e != nil {
+			t.Fatal(e)
+		}
+		start := time.Now()
+		for _, c := range cases {
+			body := proxyGet(t, c.addr, c.header)
+			ip := strings.Split(body, "|")[0]
+			remote := strings.TrimPrefix(strings.TrimPrefix(body, ip+"|"), "[")
+			if ip != c.ip || !strings.HasPrefix(remote, ip) {
+				t.Fatalf("[%s] %q expect client ip %s, %s giving", backend, c.header, c.ip, body)
+			}
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("[%s] requests should not wait for idle connection, %s taken", backend, elapsed)
+		}
+		idle.Close()
+
+		cancel()
+		if e := <-errc; e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	if _, e := http.NewProxyListener(nil, "10.0.0.0/33"); e == nil {
+		t.Fatal("invalid trusted proxy should fail")
+	}
+	if _, e := http.NewProxyListener(nil); e == nil {
+		t.Fatal("proxy listener without trusted proxies should fail")
+	}
+}
+
+//proxyGet request /ip over raw connection prefixed by header, body of response
+func proxyGet(t *testing.T, addr net.Addr, header []byte) string {
+	c, e := net.Dial("tcp", addr.String())
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+
+	c.Write(append(header, "GET /ip HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"...))
+	resp, _ := io.ReadAll(c)
+	if i := strings.Index(string(resp), "\r\n\r\n"); i >= 0 {
+		return string(resp[i+4:])
+	}
+
+	return ""
+}
+
+func TestServer_GracefulRestart(t *testing.T) {
+	if http.Inherited() {
+		serveRestarted(t)
+		return
+	}
+
+	args := os.Args
+	defer func() { os.Args = args }()
+	// restarted process runs this test only
+	os.Args = []string{args[0], "-test.run=^TestServer_GracefulRestart$"}
+
+	for _, backend := range []string{"fasthttp", "net/http"} {
+		ln, e := net.Listen("tcp", "127.0.0.1:0")
+		if e != nil {
+			t.Fatal(e)
+		}
+		addr := ln.Addr().String()
+		os.Setenv("TEST_RESTART_ADDR", addr)
+		os.Setenv("TEST_RESTART_BACKEND", backend)
+
+		s := restartServer(backend, "parent")
+		s.Kernel().Wrapper().Get("/slow", func() string {
+			time.Sleep(300 * time.Millisecond)
+			return "slow"
+		})
+		errc := make(chan error, 1)
+		go func() {
+			errc <- s.ServeListener(context.Background(), ln)
+		}()
+		<-s.Ready()
+
+		slow := make(chan string, 1)
+		go func() {
+			slow <- get(t, "http://"+addr+"/slow")
+		}()
+		time.Sleep(100 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if e := s.GracefulRestart(ctx); e != nil {
+			t.Fatal(e)
+		}
+		cancel()
+		if body := <-slow; body != "slow" {
+			t.Fatalf("[%s] request in flight should be drained, %q giving", backend, body)
+		}
+		if e := <-errc; e != nil {
+			t.Fatal(e)
+		}
+		if body := get(t, "http://"+addr+"/who"); body != "child" {
+			t.Fatalf("[%s] restarted process should serve, %q giving", backend, body)
+		}
+		get(t, "http://"+addr+"/quit")
+	}
+}
+
+//serveRestarted serve inherited listener in restarted process until "/quit" requested
+func serveRestarted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := restartServer(os.Getenv("TEST_RESTART_BACKEND"), "child")
+	s.Kernel().Wrapper().Get("/quit", func() string {
+		cancel()
+		return "bye"
+	})
+	if e := s.Start(ctx, os.Getenv("TEST_RESTART_ADDR")); e != nil {
+		t.Fatal(e)
+	}
+}
+
+func restartServer(backend, who string) *http.Server {
+	s := http.NewServer(func(request contracts.RequestContract) container.Interface {
+		return container.New()
+	}, false)
+	if backend == "net/http" {
+		s.Kernel().Handler = http.HandlerNetHttp
+	}
+	s.Kernel().Wrapper().Get("/who", func() string { return who })
+
+	return s
+}
+
+func get(t *testing.T, url string) string {
+	client := &nethttp.Client{Transport: &nethttp.Transport{DisableKeepAlives: true}}
+	resp, e := client.Get(url)
+	if e != nil {
+		t.Error(e)
+		return ""
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	return string(body)
+}
+
+//writeCert write self-signed certificate of serial
+func writeCert(t *testing.T, certFile, keyFile string, serial int64) {
+	key, e := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if e != nil {
+		t.Fatal(e)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, e := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if e != nil {
+		t.Fatal(e)
+	}
+	keyDer, e := x509.MarshalECPrivateKey(key)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+}
+
+func mustParse(t *testing.T, der []byte) *x509.Certificate {
+	cert, e := x509.ParseCertificate(der)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	return cert
+}
+
 func init() {
 	k = http.NewKernel(func(request contracts.RequestContract) container.Interface {
 
@@ -137,4 +844,35 @@ func init() {
 	w.Get("/mid2", func() string {
 		return "ok"
 	}).Middleware("test2")
+
+	w.APIResource("articles", ArticleController{})
+
+	router.Model(w, "", func(r contracts.RequestContract, raw []byte) (*User, error) {
+		if string(raw) == "1" {
+			return &User{ID: "1", Name: "enorith"}, nil
+		}
+		return nil, nil
+	})
+	w.Get("/users/{user}", func(user *User) string {
+		return user.Name
+	})
+
+	w.SigningKeys([]byte("secret"), []byte("old-secret"))
+	w.Get("/unsubscribe/{user}", func() string {
+		return "ok"
+	}).Name("unsubscribe").Middleware("signed")
+
+	admin := router.NewWrapper()
+	admin.Get("/users/:id", func(tenant content.Param, id content.ParamInt64) string {
+		return fmt.Sprintf("%s %d", tenant, id)
+	})
+	w.Mount("/tenants/{tenant}/admin", admin).Middleware("test")
+
+	w.Mount("/std/tenants/{tenant}", nethttp.HandlerFunc(func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		fmt.Fprintf(rw, "%s %s", r.URL.Path, router.MountParams(r)["tenant"])
+	}))
+	w.Mount("/fast", func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(201)
+		fmt.Fprintf(ctx, "%s %s", ctx.Method(), ctx.Path())
+	})
 }
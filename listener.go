@@ -0,0 +1,165 @@
+package http
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+//listenFdsStart first file descriptor passed by systemd socket activation
+const listenFdsStart = 3
+
+//Binding listener served by server, routes of Kernel are served if given
+type Binding struct {
+	// Addr "host:port", "unix:/path/to.sock" or "fd:3" of inherited file descriptor
+	Addr string
+	// Listener pre-opened listener, Addr is ignored if given
+	Listener net.Listener
+	// Kernel serving the listener, kernel of server if nil
+	Kernel *Kernel
+	// TLS config of listener, plaintext if nil
+	TLS *tls.Config
+	// ProxyProtocol parse PROXY protocol headers from TrustedProxies, IPs or CIDRs,
+	// required if ProxyProtocol, "*" trusts all sources
+	ProxyProtocol  bool
+	TrustedProxies []string
+}
+
+//key of listener passed to restarted process, Addr or address of pre-opened listener
+func (b Binding) key(ln net.Listener) string {
+	if b.Listener != nil || b.Addr == "" {
+		return ln.Addr().String()
+	}
+
+	return b.Addr
+}
+
+func (b Binding) listen() (net.Listener, error) {
+	ln := b.Listener
+	if ln == nil {
+		var e error
+		if ln, e = Listen(b.Addr); e != nil {
+			return nil, e
+		}
+	}
+	if !b.ProxyProtocol {
+		return ln, nil
+	}
+
+	pl, e := NewProxyListener(ln, b.TrustedProxies...)
+	if e != nil {
+		ln.Close()
+		return nil, e
+	}
+
+	return pl, nil
+}
+
+//Listen listen addr, "unix:" prefix for unix domain socket, "fd:" prefix for inherited file descriptor,
+// stale socket file is removed before listening, socket in use is never removed. Listener passed by restarted process is taken first
+func Listen(addr string) (net.Listener, error) {
+	if ln := inheritedListener(addr); ln != nil {
+		return ln, nil
+	}
+
+	var ln net.Listener
+	var e error
+	switch {
+	case strings.HasPrefix(addr, "unix:"):
+		path := strings.TrimPrefix(addr, "unix:")
+		if e = removeStaleSocket(path); e == nil {
+			ln, e = net.Listen("unix", path)
+		}
+	case strings.HasPrefix(addr, "fd:"):
+		fd, pe := strconv.Atoi(strings.TrimPrefix(addr, "fd:"))
+		if pe != nil {
+			return nil, fmt.Errorf("listen %s error: invalid file descriptor", addr)
+		}
+		ln, e = fileListener(fd, addr)
+	default:
+		ln, e = net.Listen("tcp", addr)
+	}
+	if e != nil {
+		return nil, fmt.Errorf("listen %s error: %w", addr, e)
+	}
+
+	return ln, nil
+}
+
+//removeStaleSocket remove socket file left by exited process, EADDRINUSE if it is accepting connections
+// or can't be checked
+func removeStaleSocket(path string) error {
+	if info, e := os.Stat(path); e != nil || info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+
+	c, e := net.DialTimeout("unix", path, time.Second)
+	if e == nil {
+		c.Close()
+		return syscall.EADDRINUSE
+	}
+	if !errors.Is(e, syscall.ECONNREFUSED) {
+		return syscall.EADDRINUSE
+	}
+
+	return os.Remove(path)
+}
+
+//SystemdListeners listeners passed by systemd socket activation, keyed by names of LISTEN_FDNAMES.
+// Environment variables are unset, so child processes do not inherit them
+// 	ls, _ := http.SystemdListeners()
+// 	s.ServeListener(ctx, ls["http"])
+//
+func SystemdListeners() (map[string]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	if pid, e := strconv.Atoi(os.Getenv("LISTEN_PID")); e != nil || pid != os.Getpid() {
+		return nil, errors.New("http: no socket passed by systemd")
+	}
+	n, e := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if e != nil || n < 1 {
+		return nil, errors.New("http: no socket passed by systemd")
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	listeners := make(map[string]net.Listener, n)
+	for i := 0; i < n; i++ {
+		fd := listenFdsStart + i
+		name := "fd:" + strconv.Itoa(fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		ln, e := fileListener(fd, name)
+		if e != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("listen %s error: %w", name, e)
+		}
+		listeners[name] = ln
+	}
+
+	return listeners, nil
+}
+
+//fileListener listener of inherited file descriptor, descriptor is closed after duplicated by net
+func fileListener(fd int, name string) (net.Listener, error) {
+	f := os.NewFile(uintptr(fd), name)
+	if f == nil {
+		return nil, fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	defer f.Close()
+
+	return net.FileListener(f)
+}
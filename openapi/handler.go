@@ -0,0 +1,179 @@
+package openapi
+
+import (
+	stdJson "encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/enorith/http/content"
+	"github.com/enorith/http/contracts"
+	"github.com/enorith/http/router"
+)
+
+//SwaggerUIVersion exact version of swagger-ui-dist loaded from unpkg CDN, unless Options.UIAssets given
+var SwaggerUIVersion = "5.17.14"
+
+//SwaggerUIIntegrity subresource integrity of "swagger-ui.css" and "swagger-ui-bundle.js" of SwaggerUIVersion,
+// integrity attribute is omitted if not set
+// 	openapi.SwaggerUIIntegrity["swagger-ui-bundle.js"] = "sha384-..."
+//
+var SwaggerUIIntegrity = map[string]string{}
+
+//uiAssets files of swagger-ui-dist served from Options.UIAssets
+var uiAssets = []string{"swagger-ui.css", "swagger-ui-bundle.js"}
+
+//Options of serving document
+type Options struct {
+	Info Info
+	// Path of document, YAML is served if path ends with ".yaml" or ".yml", default "/openapi.json"
+	Path string
+	// UIPath of swagger UI page, disabled if empty
+	UIPath string
+	// UIAssets files of swagger-ui-dist supplied by application, not shipped with this package,
+	// served under UIPath for offline and CSP. Assets are loaded from unpkg CDN if nil
+	// 	//go:embed swagger-ui
+	// 	var assets embed.FS
+	// 	sub, _ := fs.Sub(assets, "swagger-ui")
+	// 	openapi.Serve(w, openapi.Options{UIPath: "/docs", UIAssets: sub})
+	//
+	UIAssets fs.FS
+}
+
+//Serve document and swagger UI of routes registered to w, document is generated on request,
+// so routes registered later are included. Swagger UI is loaded from unpkg CDN unless UIAssets given
+// 	openapi.Serve(w, openapi.Options{Info: openapi.Info{Title: "API", Version: "1.0"}, UIPath: "/docs"})
+//
+func Serve(w *router.Wrapper, opts Options) {
+	if opts.Path == "" {
+		opts.Path = "/openapi.json"
+	}
+
+	w.HandleGet(opts.Path, Handler(w, opts.Info, isYAML(opts.Path)))
+	if opts.UIPath == "" {
+		return
+	}
+	if opts.UIAssets == nil {
+		w.HandleGet(opts.UIPath, UIHandler(opts.Info.Title, opts.Path))
+		return
+	}
+
+	base := strings.TrimSuffix(opts.UIPath, "/")
+	w.HandleGet(opts.UIPath, uiHandler(opts.Info.Title, opts.Path, base))
+	w.HandleGet(base+"/swagger-initializer.js", initializerHandler(opts.Path))
+	for _, name := range uiAssets {
+		w.HandleGet(base+"/"+name, AssetHandler(opts.UIAssets, name))
+	}
+}
+
+//Handler responses document of routes in JSON or YAML
+func Handler(w *router.Wrapper, info Info, yaml bool) router.RouteHandler {
+	return func(r contracts.RequestContract) contracts.ResponseContract {
+		doc, e := Generate(w, info)
+		if e != nil {
+			return content.ErrResponseFromError(e, http.StatusInternalServerError, nil)
+		}
+
+		var b []byte
+		contentType := "application/json"
+		if yaml {
+			b, e = doc.YAML()
+			contentType = "application/yaml"
+		} else {
+			b, e = doc.JSON()
+		}
+		if e != nil {
+			return content.ErrResponseFromError(e, http.StatusInternalServerError, nil)
+		}
+
+		return content.NewResponse(b, map[string]string{"Content-Type": contentType}, http.StatusOK)
+	}
+}
+
+var uiTemplate = template.Must(template.New("swagger-ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{ .Title }}</title>
+{{- if .Base }}
+  <link rel="stylesheet" href="{{ .Base }}/swagger-ui.css">
+{{- else }}
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@{{ .Version }}/swagger-ui.css"{{ with index .Integrity "swagger-ui.css" }} integrity="{{ . }}"{{ end }} crossorigin>
+{{- end }}
+</head>
+<body>
+  <div id="swagger-ui"></div>
+{{- if .Base }}
+  <script src="{{ .Base }}/swagger-ui-bundle.js"></script>
+  <script src="{{ .Base }}/swagger-initializer.js"></script>
+{{- else }}
+  <script src="https://unpkg.com/swagger-ui-dist@{{ .Version }}/swagger-ui-bundle.js"{{ with index .Integrity "swagger-ui-bundle.js" }} integrity="{{ . }}"{{ end }} crossorigin></script>
+  <script>
+    window.onload = function () {
+      window.ui = SwaggerUIBundle({ url: {{ .URL }}, dom_id: "#swagger-ui" });
+    };
+  </script>
+{{- end }}
+</body>
+</html>
+`))
+
+//UIHandler responses swagger UI page of document url, assets are loaded from unpkg CDN
+func UIHandler(title, url string) router.RouteHandler {
+	return uiHandler(title, url, "")
+}
+
+//uiHandler swagger UI page, assets and initializer are loaded from base if not empty
+func uiHandler(title, url, base string) router.RouteHandler {
+	if title == "" {
+		title = "API Document"
+	}
+
+	return func(r contracts.RequestContract) contracts.ResponseContract {
+		var b strings.Builder
+		data := map[string]interface{}{
+			"Title": title, "URL": url, "Base": base, "Version": SwaggerUIVersion, "Integrity": SwaggerUIIntegrity,
+		}
+		if e := uiTemplate.Execute(&b, data); e != nil {
+			return content.ErrResponseFromError(fmt.Errorf("swagger ui: %s", e), http.StatusInternalServerError, nil)
+		}
+
+		return content.NewResponse([]byte(b.String()), content.HtmlHeader(), http.StatusOK)
+	}
+}
+
+//initializerHandler script starting swagger UI of document url, served as file for CSP without inline script
+func initializerHandler(url string) router.RouteHandler {
+	u, _ := stdJson.Marshal(url)
+	script := []byte(fmt.Sprintf("window.onload = function () {\n  window.ui = SwaggerUIBundle({ url: %s, dom_id: \"#swagger-ui\" });\n};\n", u))
+
+	return func(r contracts.RequestContract) contracts.ResponseContract {
+		return content.NewResponse(script, map[string]string{"Content-Type": "text/javascript; charset=utf-8"}, http.StatusOK)
+	}
+}
+
+//AssetHandler responses file name of assets, eg: bundled swagger-ui-dist files
+func AssetHandler(assets fs.FS, name string) router.RouteHandler {
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	return func(r contracts.RequestContract) contracts.ResponseContract {
+		b, e := fs.ReadFile(assets, name)
+		if e != nil {
+			return content.ErrResponseFromError(fmt.Errorf("swagger ui asset %s: %s", name, e), http.StatusNotFound, nil)
+		}
+
+		return content.NewResponse(b, map[string]string{"Content-Type": contentType}, http.StatusOK)
+	}
+}
+
+func isYAML(path string) bool {
+	return strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")
+}
@@ -0,0 +1,268 @@
+//Package openapi generate OpenAPI 3.1 document from routes of router.Wrapper,
+// inputs are reflected from handler signatures and request structs
+package openapi
+
+import (
+	stdJson "encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/enorith/http/router"
+	"gopkg.in/yaml.v3"
+)
+
+const Version = "3.1.0"
+
+//Document OpenAPI document
+type Document struct {
+	OpenAPI string              `json:"openapi" yaml:"openapi"`
+	Info    Info                `json:"info" yaml:"info"`
+	Servers []Server            `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Paths   map[string]PathItem `json:"paths" yaml:"paths"`
+}
+
+type Info struct {
+	Title       string `json:"title" yaml:"title"`
+	Version     string `json:"version" yaml:"version"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+type Server struct {
+	URL         string `json:"url" yaml:"url"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+//PathItem operations of path, keyed by lower case method
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+	Tags        []string             `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses" yaml:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name" yaml:"name"`
+	In       string  `json:"in" yaml:"in"`
+	Required bool    `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema   *Schema `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty" yaml:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content" yaml:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string                `json:"description" yaml:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+//Schema subset of JSON schema, type is string or []string (nullable)
+type Schema struct {
+	Type                 interface{}        `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+}
+
+func (d *Document) JSON() ([]byte, error) {
+	return stdJson.MarshalIndent(d, "", "  ")
+}
+
+func (d *Document) YAML() ([]byte, error) {
+	return yaml.Marshal(d)
+}
+
+//operationMethods methods described as operations, HEAD and OPTIONS are answered by router,
+// methods out of specification (eg: WebDAV methods) are skipped
+var operationMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "TRACE": true,
+}
+
+//anyOperations operations of routes registered with ANY, TRACE is registered explicitly
+var anyOperations = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+//CollisionError operations of same path and method from routes of different domains,
+// paths of document are not keyed by domain
+type CollisionError []string
+
+func (e CollisionError) Error() string {
+	return "colliding operations:\n\t" + strings.Join(e, "\n\t")
+}
+
+//Generate document of routes registered to w, CollisionError if routes of different domains
+// share path and method, the first one is documented
+func Generate(w *router.Wrapper, info Info) (*Document, error) {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+	}
+
+	var collisions CollisionError
+	// domain of documented operations, keyed by method and path
+	domains := make(map[string]string)
+	ids := make(map[string]bool)
+	for _, ri := range w.RouteInfos() {
+		methods := []string{ri.Method}
+		if ri.Method == router.AnyMethod {
+			methods = anyOperations
+		}
+
+		for _, method := range methods {
+			if !operationMethods[method] {
+				continue
+			}
+
+			variants := pathsOf(ri.Path)
+			for i, v := range variants {
+				key := method + " " + v.path
+				if domain, ok := domains[key]; ok {
+					collisions = append(collisions, fmt.Sprintf("%s of domain [%s] collides with domain [%s]", key, ri.Domain, domain))
+					continue
+				}
+				domains[key] = ri.Domain
+
+				item, ok := doc.Paths[v.path]
+				if !ok {
+					item = make(PathItem)
+					doc.Paths[v.path] = item
+				}
+
+				op := &Operation{
+					Parameters: v.params,
+					Responses:  map[string]*Response{},
+				}
+				if i == len(variants)-1 {
+					// operation id is unique, taken by path of all optional parameters
+					op.OperationID = operationID(ids, ri.Name, method)
+				}
+				if ri.Domain != "" {
+					op.Tags = []string{ri.Domain}
+				}
+				describe(op, method, signatureOf(ri.Action))
+				item[strings.ToLower(method)] = op
+			}
+		}
+	}
+	if len(collisions) > 0 {
+		return doc, collisions
+	}
+
+	return doc, nil
+}
+
+//operationID unique id of route name, suffixed with method if taken, eg: other methods of route
+// 	photos.update, photos.update.patch
+func operationID(ids map[string]bool, name, method string) string {
+	if name == "" {
+		return ""
+	}
+
+	id := name
+	if ids[id] {
+		id = name + "." + strings.ToLower(method)
+	}
+	for n := 2; ids[id]; n++ {
+		id = fmt.Sprintf("%s.%s%d", name, strings.ToLower(method), n)
+	}
+	ids[id] = true
+
+	return id
+}
+
+//pathVariant OpenAPI path template and path parameters
+type pathVariant struct {
+	path   string
+	params []*Parameter
+}
+
+//pathsOf OpenAPI paths of route path, one for each count of trailing optional parameters present
+func pathsOf(routePath string) []pathVariant {
+	segments, e := router.PathSegments(routePath)
+	if e != nil {
+		return []pathVariant{{path: routePath}}
+	}
+
+	var variants []pathVariant
+	for n, parts := range segments {
+		if len(parts) == 1 && parts[0].Optional {
+			variants = append(variants, pathOf(segments[:n]))
+		}
+	}
+
+	return append(variants, pathOf(segments))
+}
+
+//pathOf OpenAPI path template and path parameters of route segments
+func pathOf(segments [][]router.PathPart) pathVariant {
+	var v pathVariant
+	var b strings.Builder
+	for _, parts := range segments {
+		b.WriteByte('/')
+		for _, part := range parts {
+			if part.Param == "" {
+				b.WriteString(part.Literal)
+				continue
+			}
+
+			schema := &Schema{Type: "string"}
+			switch part.Constraint {
+			case "int", "uint":
+				schema.Type = "integer"
+			case "uuid":
+				schema.Format = "uuid"
+			}
+			v.params = append(v.params, &Parameter{Name: part.Param, In: "path", Required: true, Schema: schema})
+			b.WriteString("{" + part.Param + "}")
+		}
+	}
+	if v.path = b.String(); v.path == "" {
+		v.path = "/"
+	}
+
+	return v
+}
+
+//signatureOf func type of route action, nil if not reflectable
+func signatureOf(action interface{}) reflect.Type {
+	switch a := action.(type) {
+	case nil, string:
+		return nil
+	case router.ControllerAction:
+		m, ok := reflect.TypeOf(a.Controller).MethodByName(a.Method)
+		if !ok {
+			return nil
+		}
+		// drop receiver
+		t := m.Type
+		in := make([]reflect.Type, t.NumIn()-1)
+		for i := range in {
+			in[i] = t.In(i + 1)
+		}
+		out := make([]reflect.Type, t.NumOut())
+		for i := range out {
+			out[i] = t.Out(i)
+		}
+		return reflect.FuncOf(in, out, t.IsVariadic())
+	case router.RouteHandler:
+		return nil
+	}
+
+	if t := reflect.TypeOf(action); t.Kind() == reflect.Func {
+		return t
+	}
+
+	return nil
+}
@@ -0,0 +1,268 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/enorith/http/content"
+	"github.com/enorith/http/openapi"
+	"github.com/enorith/http/router"
+	"github.com/enorith/http/tests"
+)
+
+type ListUsers struct {
+	content.Request
+	Status string `input:"status" validate:"required|in:active,banned"`
+	Page   string `input:"page" validate:"numeric:integer"`
+}
+
+type CreateUser struct {
+	content.JsonRequest
+	Name  string   `json:"name" validate:"required"`
+	Tags  []string `json:"tags"`
+	Score float64  `json:"score"`
+}
+
+type User struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestGenerate(t *testing.T) {
+	w := router.NewWrapper()
+	w.Get("/users", func(req ListUsers) []User { return nil }).Name("users.index")
+	w.Post("/users", func(req CreateUser) User { return User{} })
+	w.Get("/users/{id:int}", func(id content.ParamInt64) User { return User{} })
+
+	doc, e := openapi.Generate(w, openapi.Info{Title: "test", Version: "1.0"})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b, e := doc.JSON()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var out struct {
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Parameters  []struct {
+				Name     string
+				In       string
+				Required bool
+				Schema   map[string]interface{}
+			}
+			RequestBody struct {
+				Content map[string]struct {
+					Schema struct {
+						Required   []string
+						Properties map[string]map[string]interface{}
+					}
+				}
+			} `json:"requestBody"`
+		}
+	}
+	if e := json.Unmarshal(b, &out); e != nil {
+		t.Fatal(e)
+	}
+
+	index := out.Paths["/users"]["get"]
+	if index.OperationID != "users.index" || len(index.Parameters) != 2 {
+		t.Fatalf("unexpected operation %s", b)
+	}
+	status := index.Parameters[0]
+	if status.Name != "status" || status.In != "query" || !status.Required || len(status.Schema["enum"].([]interface{})) != 2 {
+		t.Fatalf("unexpected parameter %+v", status)
+	}
+	if index.Parameters[1].Schema["type"] != "integer" {
+		t.Fatalf("numeric rule should map to integer, %v giving", index.Parameters[1].Schema)
+	}
+
+	body := out.Paths["/users"]["post"].RequestBody.Content["application/json"].Schema
+	if len(body.Required) != 1 || body.Properties["tags"]["type"] != "array" || body.Properties["score"]["type"] != "number" {
+		t.Fatalf("unexpected request body %+v", body)
+	}
+
+	show := out.Paths["/users/{id}"]["get"]
+	if len(show.Parameters) != 1 || show.Parameters[0].In != "path" || show.Parameters[0].Schema["type"] != "integer" {
+		t.Fatalf("unexpected path parameters %s", b)
+	}
+
+	if _, e := doc.YAML(); e != nil {
+		t.Fatal(e)
+	}
+}
+
+type Category struct {
+	Name     string      `json:"name"`
+	Parent   *Category   `json:"parent"`
+	Children []*Category `json:"children"`
+}
+
+type CreateCategory struct {
+	content.JsonRequest
+	Name   string    `json:"name"`
+	Parent *Category `json:"parent"`
+}
+
+func TestGenerate_Recursive(t *testing.T) {
+	w := router.NewWrapper()
+	w.Get("/categories/:id", func() Category { return Category{} })
+	w.Post("/categories", func(req CreateCategory) *Category { return nil })
+
+	doc, e := openapi.Generate(w, openapi.Info{Title: "test", Version: "1.0"})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b, e := doc.JSON()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var out struct {
+		Paths map[string]map[string]struct {
+			Responses map[string]struct {
+				Content map[string]struct {
+					Schema struct {
+						Properties map[string]map[string]interface{}
+					}
+				}
+			}
+		}
+	}
+	if e := json.Unmarshal(b, &out); e != nil {
+		t.Fatal(e)
+	}
+	schema := out.Paths["/categories/{id}"]["get"].Responses["200"].Content["application/json"].Schema
+	if schema.Properties["parent"]["type"] != "object" || schema.Properties["parent"]["properties"] != nil {
+		t.Fatalf("recursive field should be described as object, %s giving", b)
+	}
+}
+
+func TestServe_UIAssets(t *testing.T) {
+	w := router.NewWrapper()
+	assets := fstest.MapFS{
+		"swagger-ui.css":       {Data: []byte("body{}")},
+		"swagger-ui-bundle.js": {Data: []byte("var SwaggerUIBundle;")},
+	}
+	openapi.Serve(w, openapi.Options{UIPath: "/docs", UIAssets: assets})
+
+	get := func(path string) string {
+		req := tests.NewRequest("GET", path)
+		p := w.Match(req)
+		if !p.IsValid() {
+			t.Fatalf("%s should be served", path)
+		}
+		return string(p.Handler()(req).Content())
+	}
+
+	page := get("/docs")
+	if strings.Contains(page, "unpkg") || strings.Contains(page, "window.onload") || !strings.Contains(page, `src="/docs/swagger-ui-bundle.js"`) {
+		t.Fatalf("page should load bundled assets without inline script, %s giving", page)
+	}
+	if get("/docs/swagger-ui-bundle.js") != "var SwaggerUIBundle;" {
+		t.Fatal("bundled asset should be served")
+	}
+	if !strings.Contains(get("/docs/swagger-initializer.js"), `"/openapi.json"`) {
+		t.Fatal("initializer should load document")
+	}
+
+	cdn := router.NewWrapper()
+	openapi.Serve(cdn, openapi.Options{UIPath: "/docs"})
+	req := tests.NewRequest("GET", "/docs")
+	if page := string(cdn.Match(req).Handler()(req).Content()); !strings.Contains(page, "swagger-ui-dist@"+openapi.SwaggerUIVersion+"/") {
+		t.Fatalf("page should load pinned version, %s giving", page)
+	}
+}
+
+func TestGenerate_PathParams(t *testing.T) {
+	w := router.NewWrapper()
+	w.Get("/posts/:year/:month?", func() string { return "" }).Name("posts.archive")
+	w.Post("/v1/items:batch", func() string { return "" })
+	w.Get("/files/{name}.{ext}", func() string { return "" })
+
+	doc, e := openapi.Generate(w, openapi.Info{Title: "test", Version: "1.0"})
+	if e != nil {
+		t.Fatal(e)
+	}
+	cases := map[string][]string{
+		"/posts/{year}":         {"year"},
+		"/posts/{year}/{month}": {"year", "month"},
+		"/v1/items:batch":       nil,
+		"/files/{name}.{ext}":   {"name", "ext"},
+	}
+	if len(doc.Paths) != len(cases) {
+		t.Fatalf("unexpected paths %v", doc.Paths)
+	}
+	for path, names := range cases {
+		item, ok := doc.Paths[path]
+		if !ok {
+			t.Fatalf("path %s should be documented", path)
+		}
+		for _, op := range item {
+			if len(op.Parameters) != len(names) {
+				t.Fatalf("[%s] expect params %v, %v giving", path, names, op.Parameters)
+			}
+			for i, p := range op.Parameters {
+				if p.Name != names[i] || !p.Required {
+					t.Fatalf("[%s] expect required param %s, %+v giving", path, names[i], p)
+				}
+			}
+		}
+	}
+	if doc.Paths["/posts/{year}/{month}"]["get"].OperationID != "posts.archive" || doc.Paths["/posts/{year}"]["get"].OperationID != "" {
+		t.Fatal("operation id should be taken by path of all optional parameters")
+	}
+}
+
+func TestGenerate_OperationIDs(t *testing.T) {
+	w := router.NewWrapper()
+	w.RegisterAction(router.PUT|router.PATCH, "/photos/{id}", func() string { return "" }).Name("photos.update")
+	w.RegisterAction(router.ANY, "/hooks/{name}", func() string { return "" }).Name("hooks")
+	w.Post("/hooks/{name}/patch", func() string { return "" }).Name("hooks.patch")
+
+	doc, e := openapi.Generate(w, openapi.Info{Title: "test", Version: "1.0"})
+	if e != nil {
+		t.Fatal(e)
+	}
+	ids := make(map[string]bool)
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			if op.OperationID == "" || ids[op.OperationID] {
+				t.Fatalf("[%s] %s operation id [%s] should be unique", method, path, op.OperationID)
+			}
+			ids[op.OperationID] = true
+		}
+	}
+	if len(ids) != 8 {
+		t.Fatalf("unexpected operation ids %v", ids)
+	}
+	if doc.Paths["/photos/{id}"]["put"].OperationID != "photos.update" || doc.Paths["/photos/{id}"]["patch"].OperationID != "photos.update.patch" {
+		t.Fatal("operation id of first method should be route name, method suffixed after the first")
+	}
+}
+
+func TestGenerate_DomainCollision(t *testing.T) {
+	w := router.NewWrapper()
+	w.Get("/users", func() string { return "" }).Name("users")
+	w.Domain("api.example.com", func(w *router.Wrapper) {
+		w.Get("/users", func() string { return "" }).Name("api.users")
+		w.Get("/teams", func() string { return "" }).Name("api.teams")
+	})
+
+	doc, e := openapi.Generate(w, openapi.Info{Title: "test", Version: "1.0"})
+	errs, ok := e.(openapi.CollisionError)
+	if !ok || len(errs) != 1 || !strings.Contains(errs[0], "GET /users") {
+		t.Fatalf("expect collision of GET /users, %v giving", e)
+	}
+	if doc.Paths["/users"]["get"].OperationID != "users" || doc.Paths["/teams"]["get"].OperationID != "api.teams" {
+		t.Fatal("first operation of colliding ones should be documented")
+	}
+
+	resp := openapi.Handler(w, openapi.Info{}, false)(tests.NewRequest("GET", "/openapi.json"))
+	if resp.StatusCode() != 500 {
+		t.Fatalf("document with collisions should response 500, %d giving", resp.StatusCode())
+	}
+}
@@ -0,0 +1,335 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/enorith/http/content"
+	"github.com/enorith/http/contracts"
+	"github.com/enorith/http/validation"
+)
+
+var (
+	typeRequest       = reflect.TypeOf(content.Request{})
+	typeJsonRequest   = reflect.TypeOf(content.JsonRequest{})
+	typeParam         = reflect.TypeOf(content.Param(""))
+	typeParamInt      = reflect.TypeOf(content.ParamInt(0))
+	typeParamInt64    = reflect.TypeOf(content.ParamInt64(0))
+	typeParamUint64   = reflect.TypeOf(content.ParamUint64(0))
+	typeTime          = reflect.TypeOf(time.Time{})
+	typeUploadFile    = reflect.TypeOf((*contracts.UploadFile)(nil)).Elem()
+	typeResponse      = reflect.TypeOf((*contracts.ResponseContract)(nil)).Elem()
+	typeError         = reflect.TypeOf((*error)(nil)).Elem()
+	typeWithValidator = reflect.TypeOf((*validation.WithValidation)(nil)).Elem()
+)
+
+//describe parameters, request body and response of operation by handler signature
+func describe(op *Operation, method string, fn reflect.Type) {
+	op.Responses["200"] = &Response{Description: "OK"}
+	if fn == nil {
+		return
+	}
+
+	paramIndex := 0
+	for i := 0; i < fn.NumIn(); i++ {
+		in := fn.In(i)
+		switch in {
+		case typeParam, typeParamInt, typeParamInt64, typeParamUint64:
+			// params are injected by position
+			if paramIndex < len(op.Parameters) && in != typeParam {
+				op.Parameters[paramIndex].Schema.Type = "integer"
+			}
+			paramIndex++
+			continue
+		}
+
+		ts := in
+		if ts.Kind() == reflect.Ptr {
+			ts = ts.Elem()
+		}
+		if ts.Kind() != reflect.Struct {
+			continue
+		}
+		if embeds(ts, typeJsonRequest) {
+			schema := objectSchema(ts, true, nil)
+			op.RequestBody = &RequestBody{
+				Required: len(schema.Required) > 0,
+				Content:  map[string]*MediaType{"application/json": {Schema: schema}},
+			}
+		} else if embeds(ts, typeRequest) {
+			requestInputs(op, method, ts)
+		}
+	}
+
+	if fn.NumOut() > 0 {
+		if media, schema := responseOf(fn.Out(0)); schema != nil {
+			op.Responses["200"].Content = map[string]*MediaType{media: {Schema: schema}}
+		}
+	}
+}
+
+//requestInputs inputs of struct embedding content.Request,
+// query parameters for GET and DELETE, form body for others
+func requestInputs(op *Operation, method string, ts reflect.Type) {
+	body := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	multipart := false
+	query := method == "GET" || method == "DELETE"
+
+	eachField(ts, func(f reflect.StructField, rules []string) {
+		if name := f.Tag.Get("param"); name != "" {
+			for _, p := range op.Parameters {
+				if p.Name == name {
+					p.Schema = schemaOf(f.Type, nil)
+					applyRules(p.Schema, rules)
+				}
+			}
+			return
+		}
+		if name := f.Tag.Get("file"); name != "" {
+			multipart = true
+			schema := &Schema{Type: "string", Format: "binary"}
+			if applyRules(schema, rules) {
+				body.Required = append(body.Required, name)
+			}
+			body.Properties[name] = schema
+			return
+		}
+
+		name := inputName(f, false)
+		if name == "" {
+			return
+		}
+		schema := schemaOf(f.Type, nil)
+		required := applyRules(schema, rules)
+		if query {
+			op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "query", Required: required, Schema: schema})
+			return
+		}
+		if required {
+			body.Required = append(body.Required, name)
+		}
+		body.Properties[name] = schema
+	})
+
+	if len(body.Properties) == 0 {
+		return
+	}
+	media := "application/x-www-form-urlencoded"
+	if multipart {
+		media = "multipart/form-data"
+	}
+	op.RequestBody = &RequestBody{
+		Required: len(body.Required) > 0,
+		Content:  map[string]*MediaType{media: {Schema: body}},
+	}
+}
+
+//eachField visit input fields of struct, fields of anonymous structs included
+func eachField(ts reflect.Type, visit func(f reflect.StructField, rules []string)) {
+	extra := map[string][]string{}
+	if ts.Implements(typeWithValidator) || reflect.PtrTo(ts).Implements(typeWithValidator) {
+		if v, ok := reflect.New(ts).Interface().(validation.WithValidation); ok {
+			for attribute, rules := range safeRules(v) {
+				for _, r := range rules {
+					if s, ok := r.(string); ok {
+						extra[attribute] = append(extra[attribute], s)
+					}
+				}
+			}
+		}
+	}
+
+	var walk func(ts reflect.Type)
+	walk = func(ts reflect.Type) {
+		for i := 0; i < ts.NumField(); i++ {
+			f := ts.Field(i)
+			if f.Type == typeRequest || f.Type == typeJsonRequest {
+				continue
+			}
+			if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag == "" {
+				walk(f.Type)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+
+			var rules []string
+			if rule := f.Tag.Get("validate"); rule != "" {
+				rules = strings.Split(rule, "|")
+			}
+			rules = append(rules, extra[inputName(f, true)]...)
+			visit(f, rules)
+		}
+	}
+	walk(ts)
+}
+
+//safeRules rules of zero value, nil if Rules panics on zero value
+func safeRules(v validation.WithValidation) (rules map[string][]interface{}) {
+	defer func() {
+		if x := recover(); x != nil {
+			rules = nil
+		}
+	}()
+
+	return v.Rules()
+}
+
+//inputName name of input field, "input" tag first, then "json"
+func inputName(f reflect.StructField, json bool) string {
+	tags := []string{"input", "json"}
+	if json {
+		tags = []string{"json", "input"}
+	}
+	for _, tag := range tags {
+		if name := strings.Split(f.Tag.Get(tag), ",")[0]; name != "" {
+			if name == "-" {
+				return ""
+			}
+			return name
+		}
+	}
+
+	return ""
+}
+
+//objectSchema schema of struct, json names used if json is true,
+// seen types are structs being described, recursive reference is described as object
+func objectSchema(ts reflect.Type, json bool, seen map[reflect.Type]bool) *Schema {
+	if seen[ts] {
+		return &Schema{Type: "object"}
+	}
+	if seen == nil {
+		seen = map[reflect.Type]bool{}
+	}
+	seen[ts] = true
+	defer delete(seen, ts)
+
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	eachField(ts, func(f reflect.StructField, rules []string) {
+		name := inputName(f, json)
+		if name == "" {
+			if !json {
+				return
+			}
+			name = f.Name
+		}
+
+		fs := schemaOf(f.Type, seen)
+		if applyRules(fs, rules) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = fs
+	})
+
+	return schema
+}
+
+//schemaOf schema of go type, seen structs are described as object
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	if t == typeUploadFile {
+		return &Schema{Type: "string", Format: "binary"}
+	}
+	if t == typeTime {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaOf(t.Elem(), seen)
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string"}
+		}
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), seen)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), seen)}
+	case reflect.Struct:
+		return objectSchema(t, true, seen)
+	}
+
+	return &Schema{}
+}
+
+//applyRules map validation rules to schema constraints, returns whether required
+func applyRules(schema *Schema, rules []string) (required bool) {
+	for _, r := range rules {
+		name, arg := r, ""
+		if i := strings.IndexByte(r, ':'); i > -1 {
+			name, arg = r[:i], r[i+1:]
+		}
+
+		switch name {
+		case "required":
+			required = true
+		case "nullable":
+			if t, ok := schema.Type.(string); ok {
+				schema.Type = []string{t, "null"}
+			}
+		case "numeric":
+			schema.Type, schema.Format = "integer", ""
+			if arg == "float" {
+				schema.Type = "number"
+			}
+		case "datetime":
+			schema.Type, schema.Format = "string", "date-time"
+		case "file":
+			schema.Type, schema.Format = "string", "binary"
+		case "in":
+			schema.Enum = nil
+			for _, v := range strings.Split(arg, ",") {
+				schema.Enum = append(schema.Enum, v)
+			}
+		}
+	}
+
+	return
+}
+
+//responseOf media type and schema of handler result
+func responseOf(t reflect.Type) (string, *Schema) {
+	if t.Implements(typeResponse) || t.Implements(typeError) {
+		return "", nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "text/plain", &Schema{Type: "string"}
+	case reflect.Struct, reflect.Map:
+		return "application/json", schemaOf(t, nil)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "", nil
+		}
+		return "application/json", schemaOf(t, nil)
+	case reflect.Ptr:
+		return responseOf(t.Elem())
+	}
+
+	return "", nil
+}
+
+func embeds(ts reflect.Type, embedded reflect.Type) bool {
+	for i := 0; i < ts.NumField(); i++ {
+		f := ts.Field(i)
+		if f.Anonymous && (f.Type == embedded || (f.Type.Kind() == reflect.Struct && embeds(f.Type, embedded))) {
+			return true
+		}
+	}
+
+	return false
+}
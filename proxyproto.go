@@ -0,0 +1,344 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//ProxyHeaderTimeout timeout of reading PROXY protocol header
+var ProxyHeaderTimeout = time.Second * 5
+
+//proxyV2Signature signature of PROXY protocol v2 header
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+//proxyV1MaxLength max length of PROXY protocol v1 header, including CRLF
+const proxyV1MaxLength = 107
+
+//ErrProxyHeader invalid or missing PROXY protocol header from trusted source
+var ErrProxyHeader = errors.New("http: invalid proxy protocol header")
+
+//ProxyListener listener parses PROXY protocol v1 and v2 headers of connections from trusted sources,
+// RemoteAddr of connections is the client address sent by proxy. Connections from untrusted sources
+// are served as they are. Headers are read in goroutine of each connection, connections are accepted
+// after header read, so slow proxies do not block accepting
+// 	ln, _ := http.NewProxyListener(ln, "10.0.0.0/8")
+// 	s.ServeListener(ctx, ln)
+//
+type ProxyListener struct {
+	net.Listener
+	trusted    []*net.IPNet
+	trustedAll bool
+
+	mu      sync.Mutex
+	pending map[net.Conn]struct{}
+	once    sync.Once
+	conns   chan net.Conn
+	errs    chan error
+	done    chan struct{}
+	closed  sync.Once
+	failed  chan struct{}
+	err     error
+}
+
+//Accept connection of which PROXY protocol header is read
+func (pl *ProxyListener) Accept() (net.Conn, error) {
+	pl.once.Do(func() {
+		go pl.accept()
+	})
+
+	select {
+	case c := <-pl.conns:
+		return c, nil
+	case e := <-pl.errs:
+		return nil, e
+	case <-pl.failed:
+		return nil, pl.err
+	case <-pl.done:
+		return nil, net.ErrClosed
+	}
+}
+
+//Close listener, connections reading header are closed
+func (pl *ProxyListener) Close() error {
+	pl.closed.Do(func() {
+		close(pl.done)
+		pl.mu.Lock()
+		for c := range pl.pending {
+			c.Close()
+		}
+		pl.mu.Unlock()
+	})
+
+	return pl.Listener.Close()
+}
+
+func (pl *ProxyListener) accept() {
+	for {
+		c, e := pl.Listener.Accept()
+		if e != nil {
+			if ne, ok := e.(net.Error); ok && ne.Temporary() {
+				select {
+				case pl.errs <- e:
+					continue
+				case <-pl.done:
+					return
+				}
+			}
+			pl.err = e
+			close(pl.failed)
+			return
+		}
+		if !pl.trust(c.RemoteAddr()) {
+			pl.deliver(c)
+			continue
+		}
+
+		go func(c net.Conn) {
+			if !pl.track(c, true) {
+				c.Close()
+				return
+			}
+			pc := &proxyConn{Conn: c, r: bufio.NewReader(c)}
+			c.SetReadDeadline(time.Now().Add(ProxyHeaderTimeout))
+			remote, local, e := readProxyHeader(pc.r)
+			c.SetReadDeadline(time.Time{})
+			pl.track(c, false)
+			if e != nil {
+				c.Close()
+				return
+			}
+			pc.remote, pc.local = remote, local
+			pl.deliver(pc)
+		}(c)
+	}
+}
+
+//track connection reading header, false if listener closed
+func (pl *ProxyListener) track(c net.Conn, reading bool) bool {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	if !reading {
+		delete(pl.pending, c)
+		return true
+	}
+	select {
+	case <-pl.done:
+		return false
+	default:
+	}
+	pl.pending[c] = struct{}{}
+
+	return true
+}
+
+func (pl *ProxyListener) deliver(c net.Conn) {
+	select {
+	case pl.conns <- c:
+	case <-pl.done:
+		c.Close()
+	}
+}
+
+func (pl *ProxyListener) trust(addr net.Addr) bool {
+	if pl.trustedAll {
+		return true
+	}
+	ta, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, n := range pl.trusted {
+		if n.Contains(ta.IP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//NewProxyListener wrap ln parsing PROXY protocol headers, trusted sources are IPs or CIDRs,
+// "*" trusts all sources, eg: listener only reachable by proxy
+func NewProxyListener(ln net.Listener, trusted ...string) (*ProxyListener, error) {
+	if len(trusted) == 0 {
+		return nil, errors.New("http: proxy protocol without trusted proxies")
+	}
+
+	pl := &ProxyListener{
+		Listener: ln,
+		pending:  make(map[net.Conn]struct{}),
+		conns:    make(chan net.Conn),
+		errs:     make(chan error),
+		done:     make(chan struct{}),
+		failed:   make(chan struct{}),
+	}
+	for _, t := range trusted {
+		if t == "*" {
+			pl.trustedAll = true
+			continue
+		}
+		if !strings.Contains(t, "/") {
+			ip := net.ParseIP(t)
+			if ip == nil {
+				return nil, fmt.Errorf("http: invalid trusted proxy %q", t)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			pl.trusted = append(pl.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, e := net.ParseCIDR(t)
+		if e != nil {
+			return nil, fmt.Errorf("http: invalid trusted proxy %q", t)
+		}
+		pl.trusted = append(pl.trusted, n)
+	}
+
+	return pl, nil
+}
+
+//proxyConn connection from trusted source of which PROXY protocol header is read
+type proxyConn struct {
+	net.Conn
+	r *bufio.Reader
+	// addresses sent by proxy, nil for LOCAL or UNKNOWN header
+	remote, local net.Addr
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	if c.r.Buffered() > 0 {
+		return c.r.Read(b)
+	}
+
+	return c.Conn.Read(b)
+}
+
+//RemoteAddr client address sent by proxy, address of proxy for LOCAL or UNKNOWN header
+func (c *proxyConn) RemoteAddr() net.Addr {
+	if c.remote != nil {
+		return c.remote
+	}
+
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyConn) LocalAddr() net.Addr {
+	if c.local != nil {
+		return c.local
+	}
+
+	return c.Conn.LocalAddr()
+}
+
+//readProxyHeader source and destination addresses of PROXY protocol header, nil for LOCAL or UNKNOWN
+func readProxyHeader(r *bufio.Reader) (src, dst net.Addr, e error) {
+	b, e := r.Peek(1)
+	if e != nil {
+		return nil, nil, e
+	}
+	switch b[0] {
+	case 'P':
+		return readProxyV1(r)
+	case '\r':
+		return readProxyV2(r)
+	}
+
+	return nil, nil, ErrProxyHeader
+}
+
+//readProxyV1 "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n"
+func readProxyV1(r *bufio.Reader) (src, dst net.Addr, e error) {
+	var line []byte
+	for len(line) < proxyV1MaxLength {
+		c, e := r.ReadByte()
+		if e != nil {
+			return nil, nil, e
+		}
+		line = append(line, c)
+		if c == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, nil, ErrProxyHeader
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) < 2 || fields[0] != "PROXY" {
+		return nil, nil, ErrProxyHeader
+	}
+	if fields[1] == "UNKNOWN" {
+		return nil, nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, nil, ErrProxyHeader
+	}
+
+	srcIP, dstIP := net.ParseIP(fields[2]), net.ParseIP(fields[3])
+	srcPort, se := strconv.ParseUint(fields[4], 10, 16)
+	dstPort, de := strconv.ParseUint(fields[5], 10, 16)
+	if srcIP == nil || dstIP == nil || se != nil || de != nil {
+		return nil, nil, ErrProxyHeader
+	}
+	if (fields[1] == "TCP4") != (srcIP.To4() != nil) {
+		return nil, nil, ErrProxyHeader
+	}
+
+	return &net.TCPAddr{IP: srcIP, Port: int(srcPort)}, &net.TCPAddr{IP: dstIP, Port: int(dstPort)}, nil
+}
+
+//readProxyV2 binary header of signature, version and command, family, length and addresses
+func readProxyV2(r *bufio.Reader) (src, dst net.Addr, e error) {
+	header := make([]byte, 16)
+	if _, e := io.ReadFull(r, header); e != nil {
+		return nil, nil, e
+	}
+	if !bytes.Equal(header[:12], proxyV2Signature) || header[12]>>4 != 2 {
+		return nil, nil, ErrProxyHeader
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, e := io.ReadFull(r, payload); e != nil {
+		return nil, nil, e
+	}
+
+	switch header[12] & 0x0f {
+	case 0x0:
+		// LOCAL, connection made by proxy itself, health check
+		return nil, nil, nil
+	case 0x1:
+	default:
+		return nil, nil, ErrProxyHeader
+	}
+
+	switch header[13] {
+	case 0x11, 0x12:
+		// TCP or UDP over IPv4
+		if len(payload) < 12 {
+			return nil, nil, ErrProxyHeader
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))},
+			&net.TCPAddr{IP: net.IP(payload[4:8]), Port: int(binary.BigEndian.Uint16(payload[10:12]))}, nil
+	case 0x21, 0x22:
+		// TCP or UDP over IPv6
+		if len(payload) < 36 {
+			return nil, nil, ErrProxyHeader
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))},
+			&net.TCPAddr{IP: net.IP(payload[16:32]), Port: int(binary.BigEndian.Uint16(payload[34:36]))}, nil
+	}
+
+	// UNSPEC or unix sockets, address of proxy is kept
+	return nil, nil, nil
+}
@@ -0,0 +1,212 @@
+package http
+
+import (
+	"context"
+	stdJson "encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+)
+
+//RestartEnv environment variable of listener addresses passed to restarted process,
+// listeners are passed as file descriptors from 3 in order, followed by the ready pipe
+var RestartEnv = "ENORITH_HTTP_LISTENERS"
+
+var (
+	inheritOnce sync.Once
+	inheritMu   sync.Mutex
+	inherited   map[string]net.Listener
+	readyPipe   *os.File
+	readyOnce   sync.Once
+)
+
+//Inherited whether listeners are inherited from the process restarted
+func Inherited() bool {
+	inheritListeners()
+
+	return readyPipe != nil
+}
+
+//inheritedListener listener of addr inherited from the process restarted, taken once
+func inheritedListener(addr string) net.Listener {
+	inheritListeners()
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+
+	ln := inherited[addr]
+	delete(inherited, addr)
+
+	return ln
+}
+
+func inheritListeners() {
+	inheritOnce.Do(func() {
+		value, ok := os.LookupEnv(RestartEnv)
+		if !ok {
+			return
+		}
+		os.Unsetenv(RestartEnv)
+
+		var addrs []string
+		if e := stdJson.Unmarshal([]byte(value), &addrs); e != nil {
+			log.Printf("%s invalid %s: %v", logPrefix("restart"), RestartEnv, e)
+			return
+		}
+		inherited = make(map[string]net.Listener, len(addrs))
+		for i, addr := range addrs {
+			ln, e := fileListener(listenFdsStart+i, addr)
+			if e != nil {
+				log.Printf("%s inherit %s error: %v", logPrefix("restart"), addr, e)
+				continue
+			}
+			inherited[addr] = ln
+		}
+		readyPipe = os.NewFile(uintptr(listenFdsStart+len(addrs)), "ready")
+	})
+}
+
+//notifyReady notify the process restarted that listeners are served
+func notifyReady() {
+	inheritListeners()
+	if readyPipe == nil {
+		return
+	}
+
+	readyOnce.Do(func() {
+		readyPipe.Write([]byte{1})
+		readyPipe.Close()
+	})
+}
+
+//Restart start new process of same executable and arguments with listeners passed,
+// returns when listeners are served by the new process. The server keeps serving until Shutdown
+func (s *Server) Restart(ctx context.Context) (*os.Process, error) {
+	s.mu.Lock()
+	addrs := append([]string(nil), s.bindAddrs...)
+	listeners := append([]net.Listener(nil), s.listeners...)
+	s.mu.Unlock()
+	if len(listeners) == 0 {
+		return nil, errors.New("http: server not started")
+	}
+
+	var files []*os.File
+	var unixListeners []*net.UnixListener
+	restarted := false
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+		for _, ul := range unixListeners {
+			// socket file is removed on closing if the new process failed
+			ul.SetUnlinkOnClose(!restarted)
+		}
+	}()
+	for i, ln := range listeners {
+		if pl, ok := ln.(*ProxyListener); ok {
+			ln = pl.Listener
+		}
+		fl, ok := ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return nil, fmt.Errorf("http: listener %s can not be passed", addrs[i])
+		}
+		f, e := fl.File()
+		if e != nil {
+			return nil, fmt.Errorf("http: listener %s can not be passed: %w", addrs[i], e)
+		}
+		files = append(files, f)
+		if ul, ok := ln.(*net.UnixListener); ok {
+			unixListeners = append(unixListeners, ul)
+		}
+	}
+
+	r, w, e := os.Pipe()
+	if e != nil {
+		return nil, e
+	}
+	defer r.Close()
+	files = append(files, w)
+
+	executable, e := os.Executable()
+	if e != nil {
+		return nil, e
+	}
+	env, _ := stdJson.Marshal(addrs)
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), RestartEnv+"="+string(env))
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	if e := cmd.Start(); e != nil {
+		return nil, fmt.Errorf("http: restart error: %w", e)
+	}
+	w.Close()
+	files = files[:len(files)-1]
+
+	ready := make(chan error, 1)
+	go func() {
+		b := make([]byte, 1)
+		if n, _ := r.Read(b); n == 1 {
+			ready <- nil
+			return
+		}
+		// pipe closed without notifying, new process exited
+		ready <- errors.New("http: restarted process exited before ready")
+	}()
+
+	select {
+	case e := <-ready:
+		if e != nil {
+			cmd.Wait()
+			return nil, e
+		}
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, ctx.Err()
+	}
+	restarted = true
+	// reap the new process if it exits before the parent
+	go cmd.Wait()
+	log.Printf("%s restarted as process %d", logPrefix(s.k.handlerName()), cmd.Process.Pid)
+
+	return cmd.Process, nil
+}
+
+//GracefulRestart restart, then drain requests in flight in ShutdownTimeout of config.
+// The server keeps serving if the new process fails
+func (s *Server) GracefulRestart(ctx context.Context) error {
+	if _, e := s.Restart(ctx); e != nil {
+		return e
+	}
+
+	dctx, cancel := context.WithTimeout(context.Background(), s.Config.resolve(s.k).ShutdownTimeout)
+	defer cancel()
+
+	return s.Shutdown(dctx)
+}
+
+//RestartOnSignal restart gracefully on signals until ctx done, eg: syscall.SIGUSR2
+// 	go s.RestartOnSignal(ctx, syscall.SIGUSR2)
+//
+func (s *Server) RestartOnSignal(ctx context.Context, sig ...os.Signal) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sig...)
+	defer signal.Stop(c)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c:
+			if e := s.GracefulRestart(ctx); e != nil {
+				log.Printf("%s restart error: %v", logPrefix(s.k.handlerName()), e)
+				continue
+			}
+			return
+		}
+	}
+}
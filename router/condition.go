@@ -0,0 +1,84 @@
+package router
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/enorith/http/contracts"
+)
+
+//Condition of route, routes of same method and path are matched by conditions
+type Condition func(r contracts.RequestContract) bool
+
+//When route matched only if all conditions hold, routes sharing path are tried in registration order,
+// route without condition should be registered last
+// 	w.Get("/users", v2Handler).Header("X-Api-Version", "2")
+// 	w.Get("/users", handler)
+//
+func (rh *routesHolder) When(c ...Condition) *routesHolder {
+	return rh.update(func(v *ParamRoute) {
+		v.conditions = append(v.conditions, c...)
+	})
+}
+
+//Accepts route matched if "Accept" header of request accepts any of media types,
+// empty "Accept" header accepts all
+func (rh *routesHolder) Accepts(mediaTypes ...string) *routesHolder {
+	return rh.When(func(r contracts.RequestContract) bool {
+		return accepts(string(r.Accepts()), mediaTypes)
+	})
+}
+
+//Header route matched if header of request equals value, or present if value is empty
+func (rh *routesHolder) Header(key, value string) *routesHolder {
+	return rh.When(func(r contracts.RequestContract) bool {
+		v := r.HeaderString(key)
+		if value == "" {
+			return v != ""
+		}
+
+		return v == value
+	})
+}
+
+func (p *ParamRoute) holds(r contracts.RequestContract) bool {
+	for _, c := range p.conditions {
+		if !c(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//accepts whether accept header accepts any of media types, "q=0" ranges are ignored
+func accepts(accept string, mediaTypes []string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, e := mime.ParseMediaType(strings.TrimSpace(part))
+		if e != nil || params["q"] == "0" || params["q"] == "0.0" {
+			continue
+		}
+		for _, t := range mediaTypes {
+			if matchMediaType(mediaRange, strings.ToLower(t)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchMediaType(mediaRange, t string) bool {
+	if mediaRange == "*/*" || mediaRange == t {
+		return true
+	}
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(t, mediaRange[:len(mediaRange)-1])
+	}
+
+	return false
+}
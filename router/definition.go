@@ -0,0 +1,241 @@
+package router
+
+import (
+	"bytes"
+	stdJson "encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//RouteDefinition route declared in routes file
+type RouteDefinition struct {
+	Path string `yaml:"path" json:"path"`
+	// Methods of route, "ANY" for all methods, default GET
+	Methods []string `yaml:"methods" json:"methods"`
+	// Action controller action, eg: "user@Show"
+	Action     string   `yaml:"action" json:"action"`
+	Name       string   `yaml:"name" json:"name"`
+	Middleware []string `yaml:"middleware" json:"middleware"`
+}
+
+//GroupDefinition group of routes declared in routes file, root of file is a group
+// 	prefix: api
+// 	middleware: [auth]
+// 	routes:
+// 	  - path: /users/{id:int}
+// 	    methods: [GET]
+// 	    action: user@Show
+// 	    name: users.show
+// 	groups:
+// 	  - prefix: admin
+// 	    name_prefix: admin.
+// 	    routes: [...]
+//
+type GroupDefinition struct {
+	Prefix     string            `yaml:"prefix" json:"prefix"`
+	Domain     string            `yaml:"domain" json:"domain"`
+	NamePrefix string            `yaml:"name_prefix" json:"name_prefix"`
+	Middleware []string          `yaml:"middleware" json:"middleware"`
+	Routes     []RouteDefinition `yaml:"routes" json:"routes"`
+	Groups     []GroupDefinition `yaml:"groups" json:"groups"`
+}
+
+//DefinitionError errors of routes definitions, all invalid routes are reported
+type DefinitionError []string
+
+func (e DefinitionError) Error() string {
+	return "invalid route definitions:\n\t" + strings.Join(e, "\n\t")
+}
+
+//ParseRouteDefinitions parse definitions of format "json" or "yaml", unknown fields are rejected
+func ParseRouteDefinitions(data []byte, format string) (*GroupDefinition, error) {
+	def := new(GroupDefinition)
+	switch strings.ToLower(format) {
+	case "json":
+		dec := stdJson.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if e := dec.Decode(def); e != nil {
+			return nil, fmt.Errorf("parse route definitions: %w", e)
+		}
+	case "yaml", "yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if e := dec.Decode(def); e != nil {
+			return nil, fmt.Errorf("parse route definitions: %w", e)
+		}
+	default:
+		return nil, fmt.Errorf("parse route definitions: unsupported format [%s]", format)
+	}
+
+	return def, nil
+}
+
+//LoadRoutes register routes declared in file, format is decided by extension (".json", ".yaml" or ".yml").
+// controllers are bound to w, controllers bound before are kept
+// 	e := w.LoadRoutes("routes.yaml", map[string]interface{}{"user": UserController{}})
+//
+func (w *Wrapper) LoadRoutes(filename string, controllers map[string]interface{}) error {
+	data, e := os.ReadFile(filename)
+	if e != nil {
+		return e
+	}
+	def, e := ParseRouteDefinitions(data, strings.TrimPrefix(filepath.Ext(filename), "."))
+	if e != nil {
+		return fmt.Errorf("%s: %w", filename, e)
+	}
+
+	if len(controllers) > 0 {
+		bound := make(map[string]interface{}, len(w.controllers)+len(controllers))
+		for name, c := range w.controllers {
+			bound[name] = c
+		}
+		for name, c := range controllers {
+			bound[name] = c
+		}
+		w.BindControllers(bound)
+	}
+
+	return w.DefineRoutes(def)
+}
+
+//DefineRoutes register routes of definitions, nothing is registered if any definition is invalid
+// or conflicts with registered routes
+func (w *Wrapper) DefineRoutes(def *GroupDefinition) error {
+	var errs DefinitionError
+	w.validateGroup(def, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	// registered to copy of routes first, so conflicts are reported instead of panicking
+	w.staging().defineGroup(def, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	w.defineGroup(def, nil)
+
+	return nil
+}
+
+//staging wrapper registers to copy of routes which is never served, attributes of w are kept
+func (w *Wrapper) staging() *Wrapper {
+	w.reg.mu.Lock()
+	set := w.reg.routes().clone()
+	w.reg.mu.Unlock()
+
+	staged := w.child()
+	staged.reg = w.reg.stage(set)
+	staged.collectors = nil
+
+	return staged
+}
+
+func (w *Wrapper) validateGroup(def *GroupDefinition, prefix string, errs *DefinitionError) {
+	prefix = JoinPaths(prefix, def.Prefix)
+	for _, rd := range def.Routes {
+		where := fmt.Sprintf("route [%s]", JoinPaths(prefix, rd.Path))
+		if rd.Path == "" {
+			*errs = append(*errs, where+": path is required")
+		}
+		if _, e := definitionMethods(rd.Methods); e != nil {
+			*errs = append(*errs, where+": "+e.Error())
+		}
+		if rd.Action == "" {
+			*errs = append(*errs, where+": action is required")
+			continue
+		}
+
+		name, method := w.parseController(rd.Action)
+		controller, exists := w.controllers[name]
+		if !exists {
+			*errs = append(*errs, fmt.Sprintf("%s: controller [%s] not registered", where, name))
+			continue
+		}
+		if !reflect.ValueOf(controller).MethodByName(method).IsValid() {
+			*errs = append(*errs, fmt.Sprintf("%s: controller [%s] (%T) has no method [%s]", where, name, controller, method))
+		}
+	}
+
+	for i := range def.Groups {
+		w.validateGroup(&def.Groups[i], prefix, errs)
+	}
+}
+
+//defineGroup register group of definition, panics are reported to errs if not nil
+func (w *Wrapper) defineGroup(def *GroupDefinition, errs *DefinitionError) {
+	if errs != nil {
+		defer func() {
+			if x := recover(); x != nil {
+				*errs = append(*errs, fmt.Sprintf("group [%s]: %v", JoinPaths(w.prefix, def.Prefix), x))
+			}
+		}()
+	}
+
+	w.GroupWith(GroupOptions{
+		Prefix:     def.Prefix,
+		Middleware: def.Middleware,
+		NamePrefix: def.NamePrefix,
+		Domain:     def.Domain,
+	}, func(gw *Wrapper) {
+		for _, rd := range def.Routes {
+			gw.defineRoute(rd, errs)
+		}
+		for i := range def.Groups {
+			gw.defineGroup(&def.Groups[i], errs)
+		}
+	})
+}
+
+//defineRoute register route of definition, panic is reported to errs if not nil
+func (w *Wrapper) defineRoute(rd RouteDefinition, errs *DefinitionError) {
+	if errs != nil {
+		defer func() {
+			if x := recover(); x != nil {
+				*errs = append(*errs, fmt.Sprintf("route [%s]: %v", JoinPaths(w.prefix, rd.Path), x))
+			}
+		}()
+	}
+
+	methods, _ := definitionMethods(rd.Methods)
+	rh := w.RegisterAction(methods, rd.Path, rd.Action)
+	if rd.Name != "" {
+		rh.Name(rd.Name)
+	}
+	if len(rd.Middleware) > 0 {
+		rh.Middleware(rd.Middleware...)
+	}
+}
+
+//definitionMethods bits of method names, only registered methods are accepted
+func definitionMethods(names []string) (int, error) {
+	if len(names) == 0 {
+		return GET, nil
+	}
+
+	bits := 0
+	for _, name := range names {
+		name = strings.ToUpper(name)
+		if name == "ANY" {
+			bits |= ANY
+			continue
+		}
+
+		bit := 0
+		for i, m := range methodsOf(ANY) {
+			if m == name {
+				bit = 1 << i
+			}
+		}
+		if bit == 0 {
+			return 0, fmt.Errorf("method [%s] not registered, register it by router.RegisterMethod", name)
+		}
+		bits |= bit
+	}
+
+	return bits, nil
+}
@@ -0,0 +1,73 @@
+package router
+
+import (
+	"strings"
+
+	"github.com/enorith/http/contracts"
+)
+
+//domain routes matched by request host, eg: "{tenant}.example.com"
+type domain struct {
+	host   string
+	tokens []token
+	rank   []uint8
+}
+
+func (d *domain) match(host string) (params Params, paramsSlice ParamSlice, ok bool) {
+	if strings.Count(host, ".")+1 != len(d.tokens) {
+		return nil, nil, false
+	}
+
+	for _, t := range d.tokens {
+		label := host
+		if i := strings.IndexByte(host, '.'); i > -1 {
+			label, host = host[:i], host[i+1:]
+		}
+
+		if !t.isParam() {
+			if !strings.EqualFold(label, t.literal) {
+				return nil, nil, false
+			}
+			continue
+		}
+
+		if label == "" || (t.constraint != nil && !t.constraint(label)) {
+			return nil, nil, false
+		}
+		params, paramsSlice = addParam(params, paramsSlice, t.name, label)
+	}
+
+	return params, paramsSlice, true
+}
+
+//newDomain parse domain of host pattern, table of domain is created at registering
+func (r *router) newDomain(host string) *domain {
+	labels := strings.Split(host, ".")
+	d := &domain{host: host, tokens: make([]token, len(labels))}
+	for i, label := range labels {
+		t := r.parseSegment(label, host)
+		if t.catchAll || t.optional || t.parts != nil {
+			panic("catch-all, optional and multiple parameters are not allowed in domain '" + host + "'")
+		}
+		d.tokens[i] = t
+	}
+	d.rank = rankOf(d.tokens)
+
+	return d
+}
+
+//hostOf request host without port
+func hostOf(request contracts.RequestContract) string {
+	var host string
+	if h, ok := request.(contracts.WithHost); ok {
+		host = h.Host()
+	} else {
+		host = request.GetURL().Host
+	}
+
+	if i := strings.LastIndexByte(host, ':'); i > -1 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+
+	return host
+}
@@ -0,0 +1,105 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/enorith/http/contracts"
+	"github.com/enorith/http/pipeline"
+)
+
+//Fallback handle requests not matching any route under prefix of group (or domain),
+// handler of the longest matched prefix is used, NotFoundHandler if none matched.
+// 	w.Group(func(w *router.Wrapper) {
+// 		w.Fallback(func() string { return "index.html" })
+// 	}, "app")
+//
+func (w *Wrapper) Fallback(handler interface{}) *routesHolder {
+	routeHandler, e := w.wrap(handler)
+	if e != nil {
+		routeHandler = invalidHandler(e)
+	}
+
+	return &routesHolder{
+		routes: []*ParamRoute{w.addFallback(routeHandler, w.actionOf(handler))},
+		reg:    w.reg,
+	}
+}
+
+func (r *router) addFallback(handler RouteHandler, action interface{}) *ParamRoute {
+	path := JoinPaths(r.prefix)
+	route := &ParamRoute{
+		path:       path,
+		handler:    handler,
+		action:     action,
+		isValid:    true,
+		domain:     r.domain,
+		middleware: append([]string(nil), r.middleware...),
+		pipeFuncs:  append([]pipeline.PipeFunc(nil), r.pipeFuncs...),
+		namePrefix: r.namePrefix,
+		pattern:    &pattern{},
+	}
+	if path != "/" {
+		route.pattern.tokens = r.tokensOf(path)
+	}
+
+	r.reg.write(func(set *routeSet) {
+		t := set.tableOf(r.domain)
+		for _, exists := range t.fallbacks {
+			if exists.pattern.key() == route.pattern.key() {
+				panic(fmt.Sprintf("a fallback is already registered for prefix '%s'", path))
+			}
+		}
+
+		fallbacks := append(t.fallbacks, route)
+		// longest prefix first, keep registration order of same length
+		for i := len(fallbacks) - 1; i > 0 && len(fallbacks[i].pattern.tokens) > len(fallbacks[i-1].pattern.tokens); i-- {
+			fallbacks[i], fallbacks[i-1] = fallbacks[i-1], fallbacks[i]
+		}
+		t.fallbacks = fallbacks
+	})
+	for _, c := range r.collectors {
+		*c = append(*c, route)
+	}
+
+	return route
+}
+
+//matchPrefix match leading segments of path
+func (p *pattern) matchPrefix(path string) (params Params, paramsSlice ParamSlice, ok bool) {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(segments) < len(p.tokens) {
+		return nil, nil, false
+	}
+
+	for i, t := range p.tokens {
+		if params, paramsSlice, ok = matchSegment(t, segments[i], params, paramsSlice); !ok {
+			return nil, nil, false
+		}
+	}
+
+	return params, paramsSlice, true
+}
+
+//fallback of the longest matched prefix, matched domains come first
+func (r *router) fallback(request contracts.RequestContract, scopes []scope, path string) *ParamRoute {
+	for _, s := range scopes {
+		for _, route := range s.table.fallbacks {
+			params, paramsSlice, ok := route.pattern.matchPrefix(path)
+			if !ok {
+				continue
+			}
+			if len(s.params) > 0 {
+				params, paramsSlice = mergeParams(s.params, s.paramsSlice, params, paramsSlice)
+			}
+
+			request.SetParams(params)
+			request.SetParamsSlice(paramsSlice)
+			request.SetRouteName(route.name)
+
+			return route
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package router
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/enorith/http/content"
+	"github.com/enorith/http/contracts"
+)
+
+//ControllerAction action of controller method
+type ControllerAction struct {
+	Controller interface{}
+	Method     string
+}
+
+//FallbackMethod method of fallback in route infos
+const FallbackMethod = "*"
+
+//AnyMethod method of routes registered with ANY in route infos
+const AnyMethod = "ANY"
+
+//RouteInfo structured information of registered route
+type RouteInfo struct {
+	Method     string   `json:"method"`
+	Domain     string   `json:"domain,omitempty"`
+	Path       string   `json:"path"`
+	Name       string   `json:"name,omitempty"`
+	Middleware []string `json:"middleware,omitempty"`
+	PipeFuncs  []string `json:"pipe_funcs,omitempty"`
+	Handler    string   `json:"handler"`
+	// Action original handler, func, ControllerAction or handler object
+	Action interface{} `json:"-"`
+}
+
+//RouteInfos information of all registered routes, sorted by domain, path and method
+func (r *router) RouteInfos() []RouteInfo {
+	var infos []RouteInfo
+	methodIndex := make(map[string]int)
+	for _, m := range methodsOf(ANY) {
+		methodIndex[m] = len(methodIndex)
+	}
+	methodIndex[AnyMethod] = len(methodIndex)
+	methodIndex[FallbackMethod] = len(methodIndex)
+
+	mounted := make(map[*mount]bool)
+	for _, t := range r.reg.routes().tables() {
+		for method, routes := range t.routes {
+			for _, route := range routes {
+				if m := route.mount; m != nil {
+					if !mounted[m] {
+						mounted[m] = true
+						infos = append(infos, mountInfos(route, m.router.RouteInfos())...)
+					}
+					continue
+				}
+				infos = append(infos, routeInfo(method, route))
+			}
+		}
+		for _, route := range t.fallbacks {
+			infos = append(infos, routeInfo(FallbackMethod, route))
+		}
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		a, b := infos[i], infos[j]
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+
+		return methodIndex[a.Method] < methodIndex[b.Method]
+	})
+
+	return infos
+}
+
+func routeInfo(method string, route *ParamRoute) RouteInfo {
+	info := RouteInfo{
+		Method:     method,
+		Path:       route.path,
+		Name:       route.name,
+		Middleware: route.middleware,
+		Handler:    HandlerName(route.action),
+		Action:     route.action,
+	}
+	if route.domain != nil {
+		info.Domain = route.domain.host
+	}
+	for _, pf := range route.pipeFuncs {
+		info.PipeFuncs = append(info.PipeFuncs, HandlerName(pf))
+	}
+
+	return info
+}
+
+//mountInfos route infos of mounted router, prefixed with mount route
+func mountInfos(route *ParamRoute, infos []RouteInfo) []RouteInfo {
+	for i, info := range infos {
+		info.Path = JoinPaths(route.mount.prefix, info.Path)
+		info.Middleware = append(append([]string{}, route.middleware...), info.Middleware...)
+		if info.Domain == "" && route.domain != nil {
+			info.Domain = route.domain.host
+		}
+		infos[i] = info
+	}
+
+	return infos
+}
+
+//RouteListHandler debug handler responses route table, json by default, text with "?format=text"
+// 	w.HandleGet("/_debug/routes", w.RouteListHandler())
+//
+func (r *router) RouteListHandler() RouteHandler {
+	return func(req contracts.RequestContract) contracts.ResponseContract {
+		infos := r.RouteInfos()
+		if req.GetURL().Query().Get("format") == "text" {
+			var b strings.Builder
+			WriteRouteTable(&b, infos)
+			return content.NewResponse([]byte(b.String()), map[string]string{
+				"Content-Type": "text/plain; charset=utf-8",
+			}, http.StatusOK)
+		}
+
+		return content.JsonResponse(infos, http.StatusOK, nil)
+	}
+}
+
+//WriteRouteTable write route infos as text table
+func WriteRouteTable(w io.Writer, infos []RouteInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tDOMAIN\tPATH\tNAME\tMIDDLEWARE\tHANDLER")
+	for _, info := range infos {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", info.Method, info.Domain, info.Path,
+			info.Name, strings.Join(info.Middleware, ","), info.Handler)
+	}
+
+	return tw.Flush()
+}
+
+//HandlerName readable name of route handler
+func HandlerName(handler interface{}) string {
+	switch h := handler.(type) {
+	case nil:
+		return ""
+	case string:
+		return h
+	case ControllerAction:
+		return fmt.Sprintf("%s@%s", reflect.TypeOf(h.Controller), h.Method)
+	case Handler:
+		return fmt.Sprintf("%s.HandleRoute", reflect.TypeOf(h))
+	case http.Handler:
+		return fmt.Sprintf("%s.ServeHTTP", reflect.TypeOf(h))
+	}
+
+	v := reflect.ValueOf(handler)
+	if v.Kind() == reflect.Func {
+		if f := runtime.FuncForPC(v.Pointer()); f != nil {
+			return f.Name()
+		}
+	}
+
+	return reflect.TypeOf(handler).String()
+}
@@ -0,0 +1,92 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/enorith/container"
+	"github.com/enorith/http/contracts"
+	httpErrors "github.com/enorith/http/errors"
+)
+
+//ErrModelNotFound returned by model resolver if model not found, responses 404
+var ErrModelNotFound = errors.New("model not found")
+
+//ModelResolver resolve model from raw route param,
+// container of request is available by r.GetContainer()
+type ModelResolver[T any] func(r contracts.RequestContract, raw []byte) (T, error)
+
+type modelBinding struct {
+	param   string
+	name    string
+	resolve func(r contracts.RequestContract, raw []byte) (interface{}, error)
+}
+
+//Model bind route param to model type, handlers could take T directly.
+// param defaults to lower case name of type,
+// nil model or ErrModelNotFound results errors.NotFound (404)
+// 	router.Model(w, "user", func(r contracts.RequestContract, raw []byte) (*User, error) {
+// 		return users.Find(string(raw))
+// 	})
+// 	w.Get("/users/{user}", func(user *User) *User { return user })
+//
+func Model[T any](w *Wrapper, param string, resolver ModelResolver[T]) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	name := typ.Name()
+	if typ.Kind() == reflect.Ptr {
+		name = typ.Elem().Name()
+	}
+	if param == "" {
+		param = strings.ToLower(name)
+	}
+
+	w.reg.models.set(typ, modelBinding{
+		param: param,
+		name:  name,
+		resolve: func(r contracts.RequestContract, raw []byte) (interface{}, error) {
+			return resolver(r, raw)
+		},
+	})
+}
+
+//BindModels bind models of matched route params to container of request
+func (w *Wrapper) BindModels(r contracts.RequestContract) {
+	models := w.reg.models.load()
+	if len(models) == 0 {
+		return
+	}
+
+	ioc := r.GetContainer()
+	params := r.Params()
+	for typ, binding := range models {
+		raw, ok := params[binding.param]
+		if !ok {
+			continue
+		}
+
+		binding := binding
+		ioc.BindFunc(typ, func(c container.Interface) (interface{}, error) {
+			model, e := binding.resolve(r, raw)
+			if errors.Is(e, ErrModelNotFound) || (e == nil && isNil(model)) {
+				return nil, httpErrors.NotFound(fmt.Sprintf("%s [%s] not found", binding.name, raw))
+			}
+
+			return model, e
+		}, true)
+	}
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return rv.IsNil()
+	}
+
+	return false
+}
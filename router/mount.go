@@ -0,0 +1,196 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/enorith/http/content"
+	"github.com/enorith/http/contracts"
+	"github.com/enorith/http/pipeline"
+	"github.com/valyala/fasthttp"
+)
+
+//mountParam catch-all parameter of mount route, holds path after prefix
+const mountParam = "mounted"
+
+type mount struct {
+	router *router
+	prefix string
+}
+
+type mountParamsKey struct{}
+
+//MountParams params of mount route, for http.Handler mounted by Wrapper.Mount.
+// For fasthttp.RequestHandler, params are set as user values of request ctx
+func MountParams(r *http.Request) Params {
+	params, _ := r.Context().Value(mountParamsKey{}).(Params)
+	return params
+}
+
+//Mount handler at prefix, prefix is stripped before handler called.
+// Handler could be http.Handler, fasthttp.RequestHandler or *Wrapper,
+// routes of mounted wrapper run through middleware of mount route first, then their own
+// 	w.Mount("/debug/pprof", http.DefaultServeMux)
+// 	w.Mount("/admin", admin).Middleware("auth")
+//
+func (w *Wrapper) Mount(prefix string, handler interface{}) *routesHolder {
+	var m *mount
+	var routeHandler RouteHandler
+	switch h := handler.(type) {
+	case *Wrapper:
+		m = &mount{router: h.router}
+		// handled by mounted router
+		routeHandler = NotFoundHandler
+	case http.Handler:
+		routeHandler = mountHttp(h)
+	case fasthttp.RequestHandler:
+		routeHandler = mountFastHttp(h)
+	case func(ctx *fasthttp.RequestCtx):
+		routeHandler = mountFastHttp(h)
+	default:
+		panic(fmt.Sprintf("mount handler expect http.Handler, fasthttp.RequestHandler or *Wrapper, %T giving", handler))
+	}
+
+	exact := w.Register(ANY, prefix, routeHandler)
+	rest := w.Register(ANY, JoinPaths(prefix, "{"+mountParam+":*}"), routeHandler)
+	if m != nil {
+		m.prefix = exact.routes[0].path
+	}
+
+	rh := &routesHolder{routes: append(exact.routes, rest.routes...), reg: w.reg}
+
+	return rh.update(func(route *ParamRoute) {
+		route.mount = m
+		route.action = handler
+	})
+}
+
+//matchMount match request in mounted router, params and attributes of mount route come first
+func matchMount(request contracts.RequestContract, route *ParamRoute, path string, params Params, paramsSlice ParamSlice) *ParamRoute {
+	rest := "/"
+	if v, ok := params[mountParam]; ok {
+		rest = string(v)
+		outer := make(Params, len(params)-1)
+		for k, v := range params {
+			if k != mountParam {
+				outer[k] = v
+			}
+		}
+		params, paramsSlice = outer, paramsSlice[:len(paramsSlice)-1]
+	}
+	base := strings.TrimSuffix(path, rest)
+
+	m := route.mount.router
+	if !m.RedirectTrailingSlash {
+		rest = string(m.normalPath([]byte(rest)))
+	}
+	inner := m.match(request, base, rest)
+
+	params, paramsSlice = mergeParams(params, paramsSlice, request.Params(), request.ParamsSlice())
+	request.SetParams(params)
+	request.SetParamsSlice(paramsSlice)
+
+	matched := &ParamRoute{
+		path:       route.path,
+		handler:    inner.handler,
+		middleware: append(append([]string{}, route.middleware...), inner.middleware...),
+		isValid:    inner.isValid,
+		pipeFuncs:  append(append([]pipeline.PipeFunc{}, route.pipeFuncs...), inner.pipeFuncs...),
+		name:       inner.name,
+		domain:     route.domain,
+		action:     inner.action,
+	}
+	if inner.path != "" {
+		matched.path = JoinPaths(route.mount.prefix, inner.path)
+	}
+	if matched.name == "" {
+		matched.name = route.name
+	}
+	request.SetRouteName(matched.name)
+
+	return matched
+}
+
+func mountedPath(r contracts.RequestContract) (rest string, params Params) {
+	rest = "/"
+	params = make(Params)
+	for k, v := range r.Params() {
+		if k == mountParam {
+			rest = string(v)
+		} else {
+			params[k] = v
+		}
+	}
+
+	return
+}
+
+func mountHttp(h http.Handler) RouteHandler {
+	return func(req contracts.RequestContract) contracts.ResponseContract {
+		rest, params := mountedPath(req)
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u := *r.URL
+			u.Path, u.RawPath = rest, ""
+			r = r.WithContext(context.WithValue(r.Context(), mountParamsKey{}, params))
+			r.URL = &u
+			r.RequestURI = u.RequestURI()
+			h.ServeHTTP(w, r)
+		})
+
+		if request, ok := req.(*content.FastHttpRequest); ok {
+			// request uri is read by adaptor, restore after handled
+			defer rewriteURI(request.Origin(), rest)()
+		}
+
+		return NewRouteHandlerFromHttp(handler)(req)
+	}
+}
+
+func mountFastHttp(h fasthttp.RequestHandler) RouteHandler {
+	return func(req contracts.RequestContract) contracts.ResponseContract {
+		rest, params := mountedPath(req)
+		handler := func(ctx *fasthttp.RequestCtx) {
+			for k, v := range params {
+				ctx.SetUserValue(k, string(v))
+			}
+			h(ctx)
+		}
+
+		if request, ok := req.(*content.FastHttpRequest); ok {
+			ctx := request.Origin()
+			restore := rewriteURI(ctx, rest)
+			handler(ctx)
+			restore()
+
+			return content.NewHandledResponse(ctx.Response.StatusCode())
+		} else if request, ok := req.(*content.NetHttpRequest); ok {
+			r := request.Origin()
+			u := *r.URL
+			u.Path, u.RawPath = rest, ""
+			r = r.Clone(r.Context())
+			r.URL = &u
+
+			return NetHttpHandlerFromFastHttp(content.NewNetHttpRequest(r, request.OriginWriter()), handler)
+		}
+
+		return content.ErrResponseFromError(errors.New("invalid handler giving"), 500, nil)
+	}
+}
+
+//rewriteURI set path of request uri, returns func to restore
+func rewriteURI(ctx *fasthttp.RequestCtx, path string) (restore func()) {
+	origin := string(ctx.Request.RequestURI())
+	uri := (&url.URL{Path: path}).EscapedPath()
+	if q := ctx.QueryArgs().QueryString(); len(q) > 0 {
+		uri += "?" + string(q)
+	}
+	ctx.Request.SetRequestURI(uri)
+
+	return func() {
+		ctx.Request.SetRequestURI(origin)
+	}
+}
@@ -1,10 +1,13 @@
 package router
 
 import (
+	"io"
+	"net"
 	"net/http"
 
 	"github.com/enorith/http/content"
 	"github.com/enorith/http/contracts"
+	"github.com/valyala/fasthttp"
 )
 
 func NetHttpHandlerFromHttp(request *content.NetHttpRequest, h http.Handler) contracts.ResponseContract {
@@ -14,3 +17,47 @@ func NetHttpHandlerFromHttp(request *content.NetHttpRequest, h http.Handler) con
 
 	return content.NewHandledResponse()
 }
+
+//NetHttpHandlerFromFastHttp serve net/http request with fasthttp handler
+func NetHttpHandlerFromFastHttp(request *content.NetHttpRequest, h fasthttp.RequestHandler) contracts.ResponseContract {
+	r, ow := request.Origin(), request.OriginWriter()
+
+	var req fasthttp.Request
+	req.Header.SetMethod(r.Method)
+	req.SetRequestURI(r.URL.RequestURI())
+	req.Header.SetHost(r.Host)
+	for k, vs := range r.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if r.Body != nil {
+		body, e := io.ReadAll(r.Body)
+		if e != nil {
+			return content.ErrResponseFromError(e, 400, nil)
+		}
+		req.SetBody(body)
+	}
+
+	var remoteAddr net.Addr
+	if addr, e := net.ResolveTCPAddr("tcp", r.RemoteAddr); e == nil {
+		remoteAddr = addr
+	}
+	var ctx fasthttp.RequestCtx
+	ctx.Init(&req, remoteAddr, nil)
+	h(&ctx)
+
+	ctx.Response.Header.VisitAll(func(k, v []byte) {
+		// computed by net/http
+		if string(k) != "Content-Length" {
+			ow.Header().Add(string(k), string(v))
+		}
+	})
+	code := ctx.Response.StatusCode()
+	ow.WriteHeader(code)
+	if r.Method != http.MethodHead {
+		ctx.Response.BodyWriteTo(ow)
+	}
+
+	return content.NewHandledResponse(code)
+}
@@ -0,0 +1,573 @@
+package router
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//Constraint check value of route parameter
+type Constraint func(value string) bool
+
+var (
+	uuidExp  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	alphaExp = regexp.MustCompile(`^[a-zA-Z]+$`)
+	alnumExp = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+)
+
+//DefaultConstraints constraints available for every router
+// 	/users/{id:int}
+//
+var DefaultConstraints = map[string]Constraint{
+	"int": func(value string) bool {
+		_, e := strconv.ParseInt(value, 10, 64)
+		return e == nil
+	},
+	"uint": func(value string) bool {
+		_, e := strconv.ParseUint(value, 10, 64)
+		return e == nil
+	},
+	"uuid":  uuidExp.MatchString,
+	"alpha": alphaExp.MatchString,
+	"alnum": alnumExp.MatchString,
+}
+
+//Regexp constraint of regular expression, value must match entirely
+func Regexp(expr string) Constraint {
+	return regexp.MustCompile("^(?:" + expr + ")$").MatchString
+}
+
+// segment rank, higher is more specific
+const (
+	rankCatchAll uint8 = iota
+	rankOptional
+	rankParam
+	rankConstrained
+	rankComposite
+	rankStatic
+)
+
+type token struct {
+	name       string
+	literal    string
+	expr       string
+	constraint Constraint
+	catchAll   bool
+	optional   bool
+	// literals and parameters of segment with multiple parameters, eg: ":name.:ext"
+	parts []token
+}
+
+func (t token) isParam() bool {
+	return t.name != ""
+}
+
+func (t token) accepts(value string) bool {
+	return value != "" && (t.constraint == nil || t.constraint(value))
+}
+
+//pattern of route path with brace parameters, matched segment by segment
+type pattern struct {
+	tokens []token
+}
+
+func (p *pattern) match(path string) (params Params, paramsSlice ParamSlice, ok bool) {
+	// segments are cut from rest, without splitting path
+	rest := strings.TrimPrefix(path, "/")
+	n := strings.Count(rest, "/") + 1
+	si := 0
+	for i, t := range p.tokens {
+		if t.catchAll {
+			end := n
+			if i < len(p.tokens)-1 {
+				// segments after catch-all are matched by rest tokens, at least one segment is caught
+				end -= len(p.tokens) - i - 1
+				if end <= si {
+					return nil, nil, false
+				}
+			} else if si >= n {
+				return nil, nil, false
+			}
+			var value string
+			value, rest = cutSegments(rest, end-si)
+			params, paramsSlice = addParam(params, paramsSlice, t.name, "/"+value)
+			si = end
+			continue
+		}
+		if si >= n {
+			// rest tokens are optional, checked at registering
+			if t.optional {
+				break
+			}
+			return nil, nil, false
+		}
+
+		var segment string
+		segment, rest = cutSegments(rest, 1)
+		params, paramsSlice, ok = matchSegment(t, segment, params, paramsSlice)
+		if !ok {
+			return nil, nil, false
+		}
+		si++
+	}
+
+	return params, paramsSlice, si == n
+}
+
+//cutSegments first k segments of path without leading slash, and segments after them
+func cutSegments(path string, k int) (head, tail string) {
+	i := 0
+	for ; k > 0; k-- {
+		j := strings.IndexByte(path[i:], '/')
+		if j < 0 {
+			return path, ""
+		}
+		i += j + 1
+	}
+
+	return path[:i-1], path[i:]
+}
+
+//matchSegment match segment by token, params of token are added
+func matchSegment(t token, segment string, params Params, paramsSlice ParamSlice) (Params, ParamSlice, bool) {
+	switch {
+	case t.parts != nil:
+		values, ok := matchParts(t.parts, segment, nil)
+		if !ok {
+			return nil, nil, false
+		}
+		i := 0
+		for _, part := range t.parts {
+			if part.isParam() {
+				params, paramsSlice = addParam(params, paramsSlice, part.name, values[i])
+				i++
+			}
+		}
+	case !t.isParam():
+		if segment != t.literal {
+			return nil, nil, false
+		}
+	default:
+		if !t.accepts(segment) {
+			return nil, nil, false
+		}
+		params, paramsSlice = addParam(params, paramsSlice, t.name, segment)
+	}
+
+	return params, paramsSlice, true
+}
+
+//matchParts match segment by parts, a parameter takes the longest value followed by next literal,
+// eg: "archive.tar.gz" of ":name.:ext" is name "archive.tar" and ext "gz"
+func matchParts(parts []token, s string, values []string) ([]string, bool) {
+	if len(parts) == 0 {
+		return values, s == ""
+	}
+
+	t := parts[0]
+	if !t.isParam() {
+		if !strings.HasPrefix(s, t.literal) {
+			return nil, false
+		}
+		return matchParts(parts[1:], s[len(t.literal):], values)
+	}
+	if len(parts) == 1 {
+		if !t.accepts(s) {
+			return nil, false
+		}
+		return append(values, s), true
+	}
+
+	// parameters are separated by literals, checked at registering
+	next := parts[1].literal
+	for i := strings.LastIndex(s, next); i > 0; i = strings.LastIndex(s[:i], next) {
+		if !t.accepts(s[:i]) {
+			continue
+		}
+		if vs, ok := matchParts(parts[1:], s[i:], append(values, s[:i])); ok {
+			return vs, true
+		}
+	}
+
+	return nil, false
+}
+
+//keys of pattern without parameter names, used to detect conflicts,
+// one key for each number of trailing optional parameters given
+func (p *pattern) keys() []string {
+	var keys []string
+	var b strings.Builder
+	for _, t := range p.tokens {
+		if t.optional {
+			keys = append(keys, b.String())
+		}
+		b.WriteByte('/')
+		writeKey(&b, t)
+	}
+
+	return append(keys, b.String())
+}
+
+//key of pattern with all parameters given
+func (p *pattern) key() string {
+	keys := p.keys()
+
+	return keys[len(keys)-1]
+}
+
+func writeKey(b *strings.Builder, t token) {
+	switch {
+	case t.catchAll:
+		b.WriteByte('*')
+	case t.parts != nil:
+		b.WriteByte('(')
+		for _, part := range t.parts {
+			writeKey(b, part)
+		}
+		b.WriteByte(')')
+	case t.isParam():
+		// constraints are compared by definition
+		b.WriteString("{" + t.expr + "}")
+	default:
+		b.WriteString(t.literal)
+	}
+}
+
+func addParam(params Params, paramsSlice ParamSlice, name, value string) (Params, ParamSlice) {
+	if params == nil {
+		params = make(Params)
+	}
+	params[name] = []byte(value)
+
+	return params, append(paramsSlice, []byte(value))
+}
+
+//parsePath parse "{name}" and "{name:constraint}" segments of path,
+// returns tree path if no brace parameter found, otherwise a pattern.
+// Brace parameters are matched segment by segment, so they can coexist with static siblings,
+// eg: "/photos/create" and "/photos/{photo}".
+// Paths beyond tree are matched as patterns as well:
+// 	/files/:name.:ext         multiple parameters in segment
+// 	/posts/:year/:month?      optional trailing segments, "{month?}" in braces
+// 	/archive/*path/edit       catch-all followed by segments
+//
+func (r *router) parsePath(path string) (treePath string, p *pattern) {
+	tokens := r.tokensOf(path)
+	optional, catchAll := false, false
+	extended := strings.Contains(path, "{")
+	for i, t := range tokens {
+		if optional && !t.optional {
+			panic("optional parameters are only allowed at the end of the path in path '" + path + "'")
+		}
+		if t.catchAll {
+			if catchAll {
+				panic("only one catch-all is allowed in path '" + path + "'")
+			}
+			extended = extended || i != len(tokens)-1
+		}
+		if t.optional && catchAll {
+			panic("optional parameters are not allowed with catch-all in path '" + path + "'")
+		}
+		optional, catchAll = optional || t.optional, catchAll || t.catchAll
+		extended = extended || t.optional || t.parts != nil
+	}
+	if !extended {
+		return path, nil
+	}
+
+	return "", &pattern{tokens: tokens}
+}
+
+//parseSegment parse segment of path, ":name", "{name}", "{name:constraint}" parameters,
+// "*name" catch-all, parameters in segment are separated by literals
+func (r *router) parseSegment(segment, path string) token {
+	if len(segment) > 1 && segment[0] == '*' {
+		return token{name: segment[1:], catchAll: true}
+	}
+
+	parts, e := segmentTokens(segment)
+	if e != nil {
+		panic(e.Error() + " in path '" + path + "'")
+	}
+	for i := range parts {
+		if !parts[i].isParam() {
+			continue
+		}
+		if parts[i].expr != "" && parts[i].expr != "*" {
+			parts[i].constraint = r.constraint(parts[i].expr)
+		}
+		if len(parts) == 1 {
+			break
+		}
+		if parts[i].catchAll || parts[i].optional {
+			panic("catch-all and optional parameters must take the whole segment '" + segment + "' in path '" + path + "'")
+		}
+		if i > 0 && parts[i-1].isParam() {
+			panic("parameters must be separated by literals in segment '" + segment + "' in path '" + path + "'")
+		}
+	}
+
+	switch len(parts) {
+	case 0:
+		return token{}
+	case 1:
+		return parts[0]
+	}
+
+	return token{parts: parts}
+}
+
+//segmentTokens literals and parameters of segment, constraints are not resolved
+func segmentTokens(segment string) ([]token, error) {
+	var tokens []token
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			tokens = append(tokens, token{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(segment); i++ {
+		c := segment[i]
+		switch {
+		case c == '{':
+			// constraint expression may contain braces, eg: {id:[0-9]{4}}
+			depth, end := 0, -1
+			for j := i; j < len(segment) && end < 0; j++ {
+				switch segment[j] {
+				case '{':
+					depth++
+				case '}':
+					if depth--; depth == 0 {
+						end = j
+					}
+				}
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("invalid parameter segment '%s'", segment)
+			}
+
+			inner := segment[i+1 : end]
+			name, expr := inner, ""
+			if j := strings.IndexByte(inner, ':'); j > -1 {
+				name, expr = inner[:j], inner[j+1:]
+			}
+			optional := strings.HasSuffix(name, "?")
+			name = strings.TrimSuffix(name, "?")
+			if name == "" {
+				return nil, fmt.Errorf("wildcards must be named with a non-empty name")
+			}
+			flush()
+			tokens = append(tokens, token{name: name, expr: expr, catchAll: expr == "*", optional: optional})
+			i = end
+		case c == '}':
+			return nil, fmt.Errorf("invalid parameter segment '%s'", segment)
+		case c == ':' && i+1 < len(segment) && (i == 0 || strings.IndexByte(".-~", segment[i-1]) > -1):
+			// ":name" starts segment or follows a separator, "items:batch" is literal
+			j := i + 1
+			for j < len(segment) && isNameChar(segment, j) {
+				j++
+			}
+			if j == i+1 {
+				literal.WriteByte(c)
+				continue
+			}
+			flush()
+			t := token{name: segment[i+1 : j]}
+			if j < len(segment) && segment[j] == '?' {
+				t.optional = true
+				j++
+			}
+			tokens = append(tokens, t)
+			i = j - 1
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	flush()
+
+	return tokens, nil
+}
+
+//PathPart literal or parameter of route path segment
+type PathPart struct {
+	Literal string
+	// Param name of parameter, empty for literal
+	Param string
+	// Constraint expression of parameter, eg: "int" of "{id:int}"
+	Constraint string
+	Optional   bool
+	CatchAll   bool
+}
+
+//PathSegments literals and parameters of each segment of route path, parsed as routes registered
+// 	PathSegments("/files/:name.:ext") // [[{Literal: "files"}] [{Param: "name"} {Literal: "."} {Param: "ext"}]]
+//
+func PathSegments(path string) ([][]PathPart, error) {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	parts := make([][]PathPart, len(segments))
+	for i, segment := range segments {
+		if len(segment) > 1 && segment[0] == '*' {
+			parts[i] = []PathPart{{Param: segment[1:], CatchAll: true}}
+			continue
+		}
+
+		tokens, e := segmentTokens(segment)
+		if e != nil {
+			return nil, fmt.Errorf("%s in path '%s'", e, path)
+		}
+		for _, t := range tokens {
+			part := PathPart{Literal: t.literal, Param: t.name, Optional: t.optional, CatchAll: t.catchAll}
+			if !t.catchAll {
+				part.Constraint = t.expr
+			}
+			parts[i] = append(parts[i], part)
+		}
+	}
+
+	return parts, nil
+}
+
+//isNameChar letters, digits, "_" and "-" not followed by another parameter
+func isNameChar(s string, i int) bool {
+	c := s[i]
+	if c == '-' {
+		return i+1 >= len(s) || s[i+1] != ':'
+	}
+
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+//constraint of expression, bare name refers to named constraint resolved at matching,
+// panics if not registered by then. Other expressions are regular expressions, "(?:json)" for literal
+func (r *router) constraint(expr string) Constraint {
+	if !isName(expr) {
+		return Regexp(expr)
+	}
+
+	reg := r.reg
+	return func(value string) bool {
+		c, ok := reg.constraints.get(expr)
+		if !ok {
+			c, ok = DefaultConstraints[expr]
+		}
+		if !ok {
+			panic("constraint [" + expr + "] not registered")
+		}
+
+		return c(value)
+	}
+}
+
+//isName identifier of named constraint, letters, digits and "_"
+func isName(expr string) bool {
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+
+	return expr != ""
+}
+
+//rankOf specificity of route path, compared segment by segment
+func rankOf(tokens []token) []uint8 {
+	rank := make([]uint8, len(tokens))
+	for i, t := range tokens {
+		switch {
+		case t.catchAll:
+			rank[i] = rankCatchAll
+		case t.optional:
+			rank[i] = rankOptional
+		case t.parts != nil:
+			rank[i] = rankComposite
+		case t.constraint != nil:
+			rank[i] = rankConstrained
+		case t.isParam():
+			rank[i] = rankParam
+		default:
+			rank[i] = rankStatic
+		}
+	}
+
+	return rank
+}
+
+func (r *router) tokensOf(path string) []token {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	tokens := make([]token, len(segments))
+	for i, segment := range segments {
+		tokens[i] = r.parseSegment(segment, path)
+	}
+
+	return tokens
+}
+
+//compareRank returns 1 if a is more specific than b, -1 if less, 0 if equal
+func compareRank(a, b []uint8) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] > b[i] {
+				return 1
+			}
+			return -1
+		}
+	}
+
+	switch {
+	case len(a) > len(b):
+		return 1
+	case len(a) < len(b):
+		return -1
+	}
+
+	return 0
+}
+
+//checkConflict panics if route of tree conflicts with patterns, or pattern conflicts with routes of tree,
+// conflicts of same kind are detected by tree and addPattern
+func (r *router) checkConflict(t *routeTable, method string, route *ParamRoute, keys []string) {
+	for _, exists := range t.routes[method] {
+		if exists == route || exists.head != nil || (exists.pattern == nil) == (route.pattern == nil) {
+			continue
+		}
+
+		var existsKeys []string
+		if exists.pattern != nil {
+			existsKeys = exists.pattern.keys()
+		} else {
+			existsKeys = (&pattern{tokens: r.tokensOf(exists.path)}).keys()
+		}
+		for _, key := range existsKeys {
+			for _, k := range keys {
+				if k == key {
+					panic(fmt.Sprintf("a route is already registered for path '%s', conflicts with '%s'", route.path, exists.path))
+				}
+			}
+		}
+	}
+}
+
+func (t *routeTable) addPattern(method string, route *ParamRoute) {
+	keys := route.pattern.keys()
+	for _, exists := range t.patterns[method] {
+		for _, key := range exists.pattern.keys() {
+			for _, k := range keys {
+				if k == key {
+					panic(fmt.Sprintf("a route is already registered for path '%s', conflicts with '%s'", route.path, exists.path))
+				}
+			}
+		}
+	}
+
+	routes := append(t.patterns[method], route)
+	// stable insertion, keep registration order of same rank
+	for i := len(routes) - 1; i > 0 && compareRank(routes[i].rank, routes[i-1].rank) > 0; i-- {
+		routes[i], routes[i-1] = routes[i-1], routes[i]
+	}
+	t.patterns[method] = routes
+	t.indexPatterns(method)
+}
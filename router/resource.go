@@ -0,0 +1,135 @@
+package router
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+//ResourceOptions options of resource routes
+type ResourceOptions struct {
+	// Only register giving actions, eg: []string{"index", "show"}
+	Only []string
+	// Except actions not to register
+	Except []string
+	// Parameters custom parameter names of resources, eg: {"people": "person"}
+	Parameters map[string]string
+}
+
+type resourceAction struct {
+	action string
+	method int
+	suffix string
+	member bool
+}
+
+var resourceActions = []resourceAction{
+	{"index", GET, "", false},
+	{"create", GET, "create", false},
+	{"store", POST, "", false},
+	{"show", GET, "", true},
+	{"edit", GET, "edit", true},
+	{"update", PUT | PATCH, "", true},
+	{"destroy", DELETE, "", true},
+}
+
+//Resource register RESTful routes of controller, actions not implemented by controller are skipped.
+// 	w.Resource("photos", PhotoController{})
+// 	// GET /photos "photos.index" -> Index
+// 	// GET /photos/create "photos.create" -> Create
+// 	// POST /photos "photos.store" -> Store
+// 	// GET /photos/{photo} "photos.show" -> Show
+// 	// GET /photos/{photo}/edit "photos.edit" -> Edit
+// 	// PUT|PATCH /photos/{photo} "photos.update" -> Update
+// 	// DELETE /photos/{photo} "photos.destroy" -> Destroy
+//
+// Nested resources are separated by ".", eg: "users.photos" registers "/users/{user}/photos/{photo}"
+func (w *Wrapper) Resource(name string, controller interface{}, opts ...ResourceOptions) *routesHolder {
+	return w.resource(name, controller, resourceActions, opts...)
+}
+
+//APIResource register resource routes without "create" and "edit"
+func (w *Wrapper) APIResource(name string, controller interface{}, opts ...ResourceOptions) *routesHolder {
+	var actions []resourceAction
+	for _, a := range resourceActions {
+		if a.action != "create" && a.action != "edit" {
+			actions = append(actions, a)
+		}
+	}
+
+	return w.resource(name, controller, actions, opts...)
+}
+
+func (w *Wrapper) resource(name string, controller interface{}, actions []resourceAction, opts ...ResourceOptions) *routesHolder {
+	var opt ResourceOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	if s, ok := controller.(string); ok {
+		c, exists := w.controllers[s]
+		if !exists {
+			panic(fmt.Sprintf("router controller [%s] not registered", s))
+		}
+		controller = c
+	}
+
+	resources := strings.Split(name, ".")
+	var base string
+	for _, parent := range resources[:len(resources)-1] {
+		base = JoinPaths(base, parent, "{"+resourceParam(parent, opt)+"}")
+	}
+	last := resources[len(resources)-1]
+	base = JoinPaths(base, last)
+	member := JoinPaths(base, "{"+resourceParam(last, opt)+"}")
+
+	typ := reflect.TypeOf(controller)
+	var routes []*ParamRoute
+	for _, a := range actions {
+		if !resourceIncluded(a.action, opt) {
+			continue
+		}
+
+		method := strings.ToUpper(a.action[:1]) + a.action[1:]
+		if _, ok := typ.MethodByName(method); !ok {
+			continue
+		}
+
+		path := base
+		if a.member {
+			path = member
+		}
+		rh := w.Register(a.method, JoinPaths(path, a.suffix), w.controllerHandler(controller, method)).
+			action(ControllerAction{Controller: controller, Method: method}).
+			Name(name + "." + a.action)
+		routes = append(routes, rh.routes...)
+	}
+
+	return &routesHolder{routes: routes, reg: w.reg}
+}
+
+func resourceIncluded(action string, opt ResourceOptions) bool {
+	if len(opt.Only) > 0 && !hasMethod(opt.Only, action) {
+		return false
+	}
+
+	return !hasMethod(opt.Except, action)
+}
+
+//resourceParam singular parameter name of resource
+func resourceParam(resource string, opt ResourceOptions) string {
+	if p, ok := opt.Parameters[resource]; ok {
+		return p
+	}
+
+	switch {
+	case strings.HasSuffix(resource, "ies"):
+		return strings.TrimSuffix(resource, "ies") + "y"
+	case strings.HasSuffix(resource, "ses"), strings.HasSuffix(resource, "xes"):
+		return strings.TrimSuffix(resource, "es")
+	case strings.HasSuffix(resource, "s") && !strings.HasSuffix(resource, "ss"):
+		return strings.TrimSuffix(resource, "s")
+	}
+
+	return resource
+}
@@ -2,6 +2,8 @@ package router
 
 import (
 	"bytes"
+	"fmt"
+	gopath "path"
 	"strings"
 	"sync"
 
@@ -19,17 +21,81 @@ const (
 	PATCH   = 1 << 4
 	DELETE  = 1 << 5
 	OPTIONS = 1 << 6
-	ANY     = GET | HEAD | POST | PUT | PATCH | DELETE | OPTIONS
+	TRACE   = 1 << 7
+	CONNECT = 1 << 8
+	//ANY every registered method at matching, including methods registered after the route,
+	// except TRACE and CONNECT which are registered explicitly
+	ANY = -1
 )
 
-var methodMap = map[int]string{
-	GET:     "GET",
-	HEAD:    "HEAD",
-	POST:    "POST",
-	PUT:     "PUT",
-	PATCH:   "PATCH",
-	DELETE:  "DELETE",
-	OPTIONS: "OPTIONS",
+//maxMethods keeps method bits in range of 32-bit int
+const maxMethods = 31
+
+var (
+	methodsMu sync.RWMutex
+	// method names ordered by bit
+	methodNames = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "TRACE", "CONNECT"}
+)
+
+//RegisterMethod register http method to be routed, returns bit of method,
+// bit of method registered before is returned as is
+// 	PROPFIND := router.RegisterMethod("PROPFIND")
+// 	w.RegisterAction(PROPFIND|router.OPTIONS, "/calendars/{user}", handler)
+//
+func RegisterMethod(method string) int {
+	method = strings.ToUpper(method)
+	methodsMu.Lock()
+	defer methodsMu.Unlock()
+
+	for i, m := range methodNames {
+		if m == method {
+			return 1 << i
+		}
+	}
+	if len(methodNames) >= maxMethods {
+		panic(fmt.Sprintf("router: too many methods, can not register %s", method))
+	}
+
+	bit := 1 << len(methodNames)
+	methodNames = append(methodNames, method)
+
+	return bit
+}
+
+//methodsOf names of registered methods in bits, ordered by bit
+func methodsOf(bits int) []string {
+	methodsMu.RLock()
+	defer methodsMu.RUnlock()
+
+	var methods []string
+	for i, m := range methodNames {
+		if bits&(1<<i) != 0 {
+			methods = append(methods, m)
+		}
+	}
+
+	return methods
+}
+
+//methodKeys methods routes of bits registered to, routes of ANY are matched by methods at matching
+func methodKeys(bits int) []string {
+	if bits == ANY {
+		return []string{AnyMethod}
+	}
+
+	return methodsOf(bits)
+}
+
+//matchesAny whether method is matched by routes of ANY, registered methods except TRACE and CONNECT
+func matchesAny(method string) bool {
+	if method == "TRACE" || method == "CONNECT" {
+		return false
+	}
+
+	methodsMu.RLock()
+	defer methodsMu.RUnlock()
+
+	return hasMethod(methodNames, method)
 }
 
 //RouteHandler normal route handler
@@ -39,6 +105,11 @@ var NotFoundHandler = func(r contracts.RequestContract) contracts.ResponseContra
 	return content.ErrResponseFromError(errors.NotFound("Not Found"), 404, nil)
 }
 
+//MethodNotAllowedHandler handle request matches path of other methods, "Allow" header is set by router
+var MethodNotAllowedHandler = func(r contracts.RequestContract) contracts.ResponseContract {
+	return content.ErrResponseFromError(errors.MethodNotAllowed("Method Not Allowed"), 405, nil)
+}
+
 type partial struct {
 	segment []byte
 	isParam bool
@@ -52,6 +123,17 @@ type ParamRoute struct {
 	isValid    bool
 	pipeFuncs  []pipeline.PipeFunc
 	name       string
+	pattern    *pattern
+	rank       []uint8
+	domain     *domain
+	namePrefix string
+	action     interface{}
+	mount      *mount
+	conditions []Condition
+	// first route registered with same method and path, nil if route is the first
+	head *ParamRoute
+	// copy replacing route modified by holder while serving, accessed by writer of registry only
+	replaced *ParamRoute
 }
 
 func (p *ParamRoute) SetMiddleware(middleware []string) *ParamRoute {
@@ -92,60 +174,116 @@ func (p *ParamRoute) Name() string {
 	return p.name
 }
 
-type routesHolder struct {
-	routes []*ParamRoute
+//Action original handler of route, func, "controller@Method" string or ControllerAction
+func (p *ParamRoute) Action() interface{} {
+	return p.action
 }
 
-func (rh *routesHolder) Middleware(middleware ...string) *routesHolder {
-	for _, v := range rh.routes {
-		ms := append(v.middleware, middleware...)
-		v.SetMiddleware(ms)
-	}
-	return rh
+//clone route modified by holder, slices are reallocated on appending
+func (p *ParamRoute) clone() *ParamRoute {
+	c := *p
+	c.middleware = p.middleware[:len(p.middleware):len(p.middleware)]
+	c.pipeFuncs = p.pipeFuncs[:len(p.pipeFuncs):len(p.pipeFuncs)]
+	c.conditions = p.conditions[:len(p.conditions):len(p.conditions)]
+
+	return &c
 }
 
-func (rh *routesHolder) Use(p ...pipeline.PipeFunc) *routesHolder {
-	for _, v := range rh.routes {
-		ps := append(v.pipeFuncs, p...)
-		v.pipeFuncs = ps
-	}
-	return rh
+//routesHolder routes registered, attributes are set on copies of routes being served,
+// so requests never match a route being modified. Routes registered while serving are matched
+// before attributes set, use GroupWith or Replace to register routes with attributes at once
+type routesHolder struct {
+	routes []*ParamRoute
+	reg    *registry
 }
 
-func (rh *routesHolder) Name(name string) *routesHolder {
-	for _, v := range rh.routes {
-		v.name = name
-	}
+//update routes of holder
+func (rh *routesHolder) update(fn func(route *ParamRoute)) *routesHolder {
+	rh.reg.modify(rh.routes, fn)
 	return rh
 }
 
-type methodTrees struct {
-	mu    *sync.RWMutex
-	nodes map[string]*node
+func (rh *routesHolder) action(action interface{}) *routesHolder {
+	return rh.update(func(v *ParamRoute) {
+		v.action = action
+	})
 }
 
-func (mt *methodTrees) get(method string) *node {
-	mt.mu.RLock()
-	defer mt.mu.RUnlock()
+func (rh *routesHolder) Middleware(middleware ...string) *routesHolder {
+	return rh.update(func(v *ParamRoute) {
+		v.middleware = append(v.middleware, middleware...)
+	})
+}
 
-	return mt.nodes[method]
+func (rh *routesHolder) Use(p ...pipeline.PipeFunc) *routesHolder {
+	return rh.update(func(v *ParamRoute) {
+		v.pipeFuncs = append(v.pipeFuncs, p...)
+	})
 }
 
-func (mt *methodTrees) set(method string, n *node) {
-	mt.mu.Lock()
-	defer mt.mu.Unlock()
+//Name of routes, prefixed with name prefix of groups
+func (rh *routesHolder) Name(name string) *routesHolder {
+	return rh.update(func(v *ParamRoute) {
+		v.name = v.namePrefix + name
+	})
+}
 
-	mt.nodes[method] = n
+//scope of matching, default table or matched domain with host params
+type scope struct {
+	table       *routeTable
+	params      Params
+	paramsSlice ParamSlice
 }
 
 type router struct {
-	routes map[string][]*ParamRoute
-	trees  *methodTrees
+	reg *registry
+	// domain routes registered to, nil for default table
+	domain *domain
 	prefix string
+
+	// group attributes, applied to registered routes
+	middleware []string
+	pipeFuncs  []pipeline.PipeFunc
+	namePrefix string
+	collectors []*[]*ParamRoute
+
+	// HandleMethodNotAllowed response 405 with "Allow" header,
+	// if the path matches routes of other methods
+	HandleMethodNotAllowed bool
+
+	// MethodNotAllowed custom handler of 405, fallback to MethodNotAllowedHandler
+	MethodNotAllowed RouteHandler
+
+	// HandleOPTIONS response OPTIONS request automatically with "Allow" header,
+	// routes registered with OPTIONS method take precedence
+	HandleOPTIONS bool
+
+	// RedirectTrailingSlash redirect to the path with (without) trailing slash,
+	// if route of path without (with) the trailing slash exists.
+	// Trailing slash is trimmed silently if disabled
+	RedirectTrailingSlash bool
+
+	// RedirectFixedPath clean path and redirect to case-insensitive matched route
+	RedirectFixedPath bool
 }
 
 func (r *router) Routes() map[string][]*ParamRoute {
-	return r.routes
+	return r.table().routes
+}
+
+//table current table routes registered to
+func (r *router) table() *routeTable {
+	set := r.reg.routes()
+	if r.domain == nil {
+		return set.table
+	}
+	for _, d := range set.domains {
+		if d.host == r.domain.host {
+			return d.table
+		}
+	}
+
+	return newRouteTable()
 }
 
 //HandleGet get method with route handler
@@ -172,32 +310,77 @@ func (r *router) HandleDelete(path string, handler RouteHandler) *routesHolder {
 //Register register route
 func (r *router) Register(method int, path string, handler RouteHandler) *routesHolder {
 	var routes []*ParamRoute
-	for i := GET; i <= OPTIONS; i <<= 1 {
-		if m, ok := methodMap[i]; i&method > 0 && ok {
-			routes = append(routes, r.addRoute(m, path, handler))
+	r.reg.write(func(set *routeSet) {
+		t := set.tableOf(r.domain)
+		for _, m := range methodKeys(method) {
+			routes = append(routes, r.addRoute(t, m, path, handler))
 		}
-	}
+	})
 
-	return &routesHolder{
-		routes,
+	return &routesHolder{routes: routes, reg: r.reg}
+}
+
+//Remove routes of method and path, safe to call while serving, ANY removes routes of every method,
+// returns whether any route removed
+func (r *router) Remove(method int, path string) bool {
+	path = JoinPaths(r.prefix, path)
+	methods := methodsOf(method)
+	if method == ANY {
+		methods = append(methods, AnyMethod)
 	}
+	removed := false
+	r.reg.write(func(set *routeSet) {
+		t := set.tableOf(r.domain)
+		for _, m := range methods {
+			if t.remove(m, path) {
+				removed = true
+			}
+		}
+	})
+
+	return removed
 }
 
-func (r *router) addRoute(method string, path string, handler RouteHandler) *ParamRoute {
+func (r *router) addRoute(t *routeTable, method string, path string, handler RouteHandler) *ParamRoute {
 	path = JoinPaths(r.prefix, path)
 	route := &ParamRoute{
-		path:    path,
-		handler: handler,
-		isValid: true,
+		path:       path,
+		handler:    handler,
+		action:     handler,
+		isValid:    true,
+		domain:     r.domain,
+		middleware: append([]string(nil), r.middleware...),
+		pipeFuncs:  append([]pipeline.PipeFunc(nil), r.pipeFuncs...),
+		namePrefix: r.namePrefix,
+	}
+	for _, c := range r.collectors {
+		*c = append(*c, route)
 	}
 
-	r.routes[method] = append(r.routes[method], route)
-	tree := r.trees.get(method)
+	if head := t.headOf(method, path); head != nil {
+		t.addCandidate(method, head, route)
+		return route
+	}
+
+	t.routes[method] = append(t.routes[method], route)
+	treePath, p := r.parsePath(path)
+	if p != nil {
+		route.pattern = p
+		route.rank = rankOf(p.tokens)
+		r.checkConflict(t, method, route, p.keys())
+		t.addPattern(method, route)
+		return route
+	}
+
+	tokens := r.tokensOf(treePath)
+	route.rank = rankOf(tokens)
+	r.checkConflict(t, method, route, (&pattern{tokens: tokens}).keys())
+	tree := t.trees.get(method)
 	if tree == nil {
 		tree = new(node)
-		r.trees.set(method, tree)
+		t.trees.set(method, tree)
 	}
-	tree.addRoute(path, route)
+	tree.addRoute(treePath, route)
 
 	return route
 }
@@ -208,18 +391,59 @@ func (r *router) Match(request contracts.RequestContract) *ParamRoute {
 }
 
 func (r *router) MatchTree(request contracts.RequestContract) *ParamRoute {
+	pathBytes := request.GetPathBytes()
+	if !r.RedirectTrailingSlash {
+		pathBytes = r.normalPath(pathBytes)
+	}
+
+	return r.match(request, "", string(pathBytes))
+}
+
+//match path of request, base is prefix of mounted router
+func (r *router) match(request contracts.RequestContract, base, sp string) *ParamRoute {
 	method := request.GetMethod()
-	sp := string(r.normalPath(request.GetPathBytes()))
-	tree := r.trees.get(method)
-
-	if tree != nil {
-		value := tree.getValue(sp)
-		if value.route != nil {
-			request.SetParams(value.params)
-			request.SetParamsSlice(value.paramsSlice)
-			request.SetRouteName(value.route.name)
-			return value.route
+	scopes := r.scopes(request)
+
+	if route := r.matchMethod(request, scopes, method, sp); route != nil {
+		return route
+	}
+
+	// HEAD fallback to GET, body will be dropped by kernel
+	if method == "HEAD" {
+		if route := r.matchMethod(request, scopes, "GET", sp); route != nil {
+			return route
+		}
+	}
+
+	if method != "CONNECT" && sp != "/" {
+		if route := r.redirect(request, scopes, method, base, sp); route != nil {
+			return route
+		}
+	}
+
+	if method == "OPTIONS" && r.HandleOPTIONS {
+		if allow := r.allowed(scopes, sp, method); len(allow) > 0 {
+			return &ParamRoute{
+				isValid: true,
+				handler: withAllowHeader(optionsHandler, allow),
+			}
 		}
+	} else if r.HandleMethodNotAllowed {
+		if allow := r.allowed(scopes, sp, method); len(allow) > 0 {
+			handler := r.MethodNotAllowed
+			if handler == nil {
+				handler = MethodNotAllowedHandler
+			}
+
+			return &ParamRoute{
+				isValid: true,
+				handler: withAllowHeader(handler, allow),
+			}
+		}
+	}
+
+	if route := r.fallback(request, scopes, sp); route != nil {
+		return route
 	}
 
 	return &ParamRoute{
@@ -228,6 +452,175 @@ func (r *router) MatchTree(request contracts.RequestContract) *ParamRoute {
 	}
 }
 
+//scopes matched domains of request host, then the default table
+func (r *router) scopes(request contracts.RequestContract) []scope {
+	set := r.reg.serve()
+	if len(set.domains) == 0 {
+		return set.defaults
+	}
+
+	host := hostOf(request)
+	var scopes []scope
+	for _, d := range set.domains {
+		if params, paramsSlice, ok := d.match(host); ok {
+			scopes = append(scopes, scope{table: d.table, params: params, paramsSlice: paramsSlice})
+		}
+	}
+
+	return append(scopes, scope{table: set.table})
+}
+
+func (r *router) matchMethod(request contracts.RequestContract, scopes []scope, method, path string) *ParamRoute {
+	for _, s := range scopes {
+		value := s.table.lookup(method, path)
+		if value.route == nil {
+			continue
+		}
+		route := s.table.choose(value.route, request)
+		if route == nil {
+			continue
+		}
+
+		params, paramsSlice := value.params, value.paramsSlice
+		if len(s.params) > 0 {
+			// host params come first
+			params, paramsSlice = mergeParams(s.params, s.paramsSlice, params, paramsSlice)
+		}
+		if route.mount != nil {
+			return matchMount(request, route, path, params, paramsSlice)
+		}
+
+		request.SetParams(params)
+		request.SetParamsSlice(paramsSlice)
+		request.SetRouteName(route.name)
+
+		return route
+	}
+
+	return nil
+}
+
+func mergeParams(params Params, paramsSlice ParamSlice, more Params, moreSlice ParamSlice) (Params, ParamSlice) {
+	merged := make(Params, len(params)+len(more))
+	for k, v := range params {
+		merged[k] = v
+	}
+	for k, v := range more {
+		merged[k] = v
+	}
+
+	return merged, append(append(ParamSlice{}, paramsSlice...), moreSlice...)
+}
+
+//redirect to canonical path, 301 for GET, 308 for other methods
+func (r *router) redirect(request contracts.RequestContract, scopes []scope, method, base, path string) *ParamRoute {
+	if !r.RedirectTrailingSlash && !r.RedirectFixedPath {
+		return nil
+	}
+
+	code := 308
+	if method == "GET" {
+		code = 301
+	}
+
+	toggled := path + "/"
+	if len(path) > 1 && path[len(path)-1] == '/' {
+		toggled = path[:len(path)-1]
+	}
+	for _, s := range scopes {
+		m := method
+		if method == "HEAD" && s.table.trees.get(m) == nil && len(s.table.patterns[m]) == 0 {
+			m = "GET"
+		}
+		methods := []string{m}
+		if s.table.hasAny() && matchesAny(method) {
+			methods = append(methods, AnyMethod)
+		}
+
+		for _, m := range methods {
+			tree := s.table.trees.get(m)
+
+			// patterns are not in tree, matched with slash toggled
+			if r.RedirectTrailingSlash && ((tree != nil && tree.getValue(path).tsr) || s.table.matchPattern(m, toggled)) {
+				return redirectRoute(request, base+toggled, code)
+			}
+
+			if r.RedirectFixedPath && tree != nil {
+				fixed, found := tree.findCaseInsensitivePath(CleanPath(path), r.RedirectTrailingSlash)
+				if found {
+					return redirectRoute(request, base+fixed, code)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func redirectRoute(request contracts.RequestContract, location string, code int) *ParamRoute {
+	if q := request.GetURL().RawQuery; q != "" {
+		location += "?" + q
+	}
+
+	return &ParamRoute{
+		isValid: true,
+		handler: func(r contracts.RequestContract) contracts.ResponseContract {
+			return content.NewResponse(nil, map[string]string{"Location": location}, code)
+		},
+	}
+}
+
+//allowed methods matches the path, except requested method
+func (r *router) allowed(scopes []scope, path, method string) (allow []string) {
+	for _, m := range methodsOf(ANY) {
+		if m == method || hasMethod(allow, m) {
+			continue
+		}
+
+		for _, s := range scopes {
+			if s.table.lookup(m, path).route == nil {
+				continue
+			}
+			allow = append(allow, m)
+			// HEAD is always answered by GET routes
+			if m == "GET" && method != "HEAD" {
+				allow = append(allow, "HEAD")
+			}
+			break
+		}
+	}
+
+	// OPTIONS is always answered when HandleOPTIONS enabled
+	if len(allow) > 0 && (r.HandleOPTIONS || method == "OPTIONS") && !hasMethod(allow, "OPTIONS") {
+		allow = append(allow, "OPTIONS")
+	}
+
+	return
+}
+
+func hasMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+func optionsHandler(r contracts.RequestContract) contracts.ResponseContract {
+	return content.NewResponse(nil, nil, 204)
+}
+
+func withAllowHeader(handler RouteHandler, allow []string) RouteHandler {
+	return func(r contracts.RequestContract) contracts.ResponseContract {
+		resp := handler(r)
+		resp.SetHeader("Allow", strings.Join(allow, ", "))
+
+		return resp
+	}
+}
+
 // Depracated MatchBytes, using MatchTree
 //
 func (r *router) MatchBytes(request contracts.RequestContract) *ParamRoute {
@@ -237,7 +630,7 @@ func (r *router) MatchBytes(request contracts.RequestContract) *ParamRoute {
 
 	partialLength := len(bytesPartials)
 
-	for _, route := range r.routes[method] {
+	for _, route := range r.reg.serve().table.routes[method] {
 		/// static match
 		if bytes.Equal([]byte(route.path), pathBytes) {
 			return route
@@ -287,7 +680,7 @@ func (r *router) MatchString(request contracts.RequestContract) *ParamRoute {
 	partials := strings.Split(sp, "/")
 	l := len(partials)
 
-	for _, route := range r.routes[method] {
+	for _, route := range r.reg.serve().table.routes[method] {
 		if route.path == sp {
 			return route
 		} else if len(route.partials) == l {
@@ -333,6 +726,20 @@ func (r *router) normalPath(path []byte) []byte {
 	return path
 }
 
+//CleanPath eliminate ".", ".." and multiple slashes, trailing slash is kept
+func CleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+
+	np := gopath.Clean(AppendSlash(p))
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+
+	return np
+}
+
 func AppendSlash(path string) string {
 	if strings.Index(path, "/") != 0 {
 		path = "/" + path
@@ -2,10 +2,13 @@ package router_test
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/enorith/http/content"
 	"github.com/enorith/http/contracts"
+	"github.com/enorith/http/pipeline"
 	router2 "github.com/enorith/http/router"
 	"github.com/enorith/http/tests"
 )
@@ -57,6 +60,33 @@ func BenchmarkRouter_MatchParamInjection(b *testing.B) {
 	}
 }
 
+func BenchmarkRouter_MatchStaticWithPatterns(b *testing.B) {
+	w := router2.NewWrapper()
+	w.Get("/health", func() string { return "ok" })
+	for i := 0; i < 16; i++ {
+		w.Get(fmt.Sprintf("/x%d/{id:int}/y", i), func() string { return "ok" })
+		w.Get(fmt.Sprintf("/files%d/:name.:ext", i), func() string { return "ok" })
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		w.Match(NewRequest("GET", "/health"))
+	}
+}
+
+func BenchmarkRouter_MatchPattern(b *testing.B) {
+	w := router2.NewWrapper()
+	for i := 0; i < 16; i++ {
+		w.Get(fmt.Sprintf("/x%d/{id:int}/y", i), func() string { return "ok" })
+	}
+	w.Get("/files/:name.:ext", func() string { return "ok" })
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		w.Match(NewRequest("GET", "/x15/42/y"))
+	}
+}
+
 func TestJoinPaths(t *testing.T) {
 	t.Log(router2.JoinPaths("", "/", "bar", "/foo"))
 }
@@ -92,3 +122,649 @@ func init() {
 	})
 
 }
+
+func TestWrapper_URL(t *testing.T) {
+	w := router2.NewWrapper()
+	w.Get("/users/:id/posts/:post", func() string { return "ok" }).Name("users.posts")
+	w.Get("/static/*filepath", func() string { return "ok" }).Name("static")
+
+	u, e := w.URL("users.posts", map[string]interface{}{"id": 42, "post": "hello world"}, url.Values{"page": {"2"}})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if u != "/users/42/posts/hello%20world?page=2" {
+		t.Fatalf("unexpected url %s", u)
+	}
+
+	u, _ = w.URL("static", map[string]interface{}{"filepath": "/css/app.css"}, nil)
+	if u != "/static/css/app.css" {
+		t.Fatalf("unexpected url %s", u)
+	}
+
+	if _, e = w.URL("users.posts", map[string]interface{}{"id": 42}, nil); e == nil {
+		t.Fatal("missing param should fail")
+	}
+	if _, e = w.URL("undefined", nil, nil); e == nil {
+		t.Fatal("undefined route should fail")
+	}
+}
+
+func TestRouter_MethodNotAllowed(t *testing.T) {
+	w := router2.NewWrapper()
+	w.Get("/users/:id", func() string { return "ok" })
+	w.Delete("/users/:id", func() string { return "ok" })
+
+	req := NewRequest("POST", "/users/42")
+	resp := w.Match(req).Handler()(req)
+	if resp.StatusCode() != 405 {
+		t.Fatalf("expect status 405, %d giving", resp.StatusCode())
+	}
+	if allow := resp.Header("Allow"); allow != "GET, HEAD, DELETE, OPTIONS" {
+		t.Fatalf("unexpected Allow header %q", allow)
+	}
+
+	req = NewRequest("POST", "/posts/42")
+	if resp = w.Match(req).Handler()(req); resp.StatusCode() != 404 {
+		t.Fatalf("expect status 404, %d giving", resp.StatusCode())
+	}
+}
+
+func TestRouter_Options(t *testing.T) {
+	w := router2.NewWrapper()
+	w.Get("/users", func() string { return "ok" })
+	w.Post("/users", func() string { return "ok" })
+	w.Register(router2.OPTIONS, "/posts", func(r contracts.RequestContract) contracts.ResponseContract {
+		return content.TextResponse("custom", 200)
+	})
+
+	req := NewRequest("OPTIONS", "/users")
+	resp := w.Match(req).Handler()(req)
+	if resp.StatusCode() != 204 || resp.Header("Allow") != "GET, HEAD, POST, OPTIONS" {
+		t.Fatalf("unexpected options response %d %q", resp.StatusCode(), resp.Header("Allow"))
+	}
+
+	req = NewRequest("OPTIONS", "/posts")
+	if resp = w.Match(req).Handler()(req); string(resp.Content()) != "custom" {
+		t.Fatalf("explicit OPTIONS route should take precedence")
+	}
+
+	w.HandleOPTIONS = false
+	req = NewRequest("OPTIONS", "/users")
+	if resp = w.Match(req).Handler()(req); resp.StatusCode() != 405 {
+		t.Fatalf("expect status 405, %d giving", resp.StatusCode())
+	}
+}
+
+func TestRouter_Head(t *testing.T) {
+	w := router2.NewWrapper()
+	w.Get("/users/:id", func() string { return "ok" }).Name("users.show")
+
+	req := NewRequest("HEAD", "/users/42")
+	if p := w.Match(req); p.Name() != "users.show" {
+		t.Fatalf("HEAD should fallback to GET route")
+	}
+}
+
+func TestRouter_Redirect(t *testing.T) {
+	w := router2.NewWrapper()
+	w.RedirectTrailingSlash = true
+	w.RedirectFixedPath = true
+	w.Get("/users", func() string { return "ok" })
+	w.Post("/posts/", func() string { return "ok" })
+	w.Get("/users/{id:int}", func() string { return "ok" })
+	w.Put("/posts/{id:int}/", func() string { return "ok" })
+
+	cases := []struct {
+		method, path, location string
+		code                   int
+	}{
+		{"GET", "/users/?page=2", "/users?page=2", 301},
+		{"POST", "/posts", "/posts/", 308},
+		{"GET", "/users/5/", "/users/5", 301},
+		{"HEAD", "/users/5/", "/users/5", 308},
+		{"PUT", "/posts/5", "/posts/5/", 308},
+		{"GET", "/USERS", "/users", 301},
+		{"GET", "/foo/../users", "/users", 301},
+	}
+
+	for _, c := range cases {
+		req := tests.NewRequest(c.method, c.path)
+		resp := w.Match(req).Handler()(req)
+		if resp.StatusCode() != c.code || resp.Header("Location") != c.location {
+			t.Fatalf("[%s] %s expect redirect %d %s, %d %s giving", c.method, c.path,
+				c.code, c.location, resp.StatusCode(), resp.Header("Location"))
+		}
+	}
+}
+
+func TestRouter_Constraints(t *testing.T) {
+	w := router2.NewWrapper()
+	w.Constraint("lower", router2.Regexp("[a-z]+"))
+	w.Get("/users/{id:int}", func() string { return "ok" }).Name("users.id")
+	w.Get("/users/{slug:[a-z-]+}", func() string { return "ok" }).Name("users.slug")
+	w.Get("/users/me", func() string { return "ok" }).Name("users.me")
+	w.Get("/tokens/{uuid:uuid}", func() string { return "ok" }).Name("tokens")
+	w.Get("/tags/{tag:lower}", func() string { return "ok" }).Name("tags")
+	w.Get("/posts/:name", func() string { return "ok" }).Name("posts.name")
+	w.Get("/posts/{id:int}", func() string { return "ok" }).Name("posts.id")
+
+	cases := map[string]string{
+		"/users/42":      "users.id",
+		"/users/foo-bar": "users.slug",
+		"/users/me":      "users.me",
+		"/users/Foo":     "",
+		"/tokens/6ba7b810-9dad-11d1-80b4-00c04fd430c8": "tokens",
+		"/tokens/42":   "",
+		"/tags/go":     "tags",
+		"/tags/Go":     "",
+		"/posts/42":    "posts.id",
+		"/posts/hello": "posts.name",
+	}
+
+	for path, name := range cases {
+		req := NewRequest("GET", path)
+		if p := w.Match(req); p.Name() != name {
+			t.Fatalf("%s expect route [%s], [%s] giving", path, name, p.Name())
+		}
+	}
+
+	req := NewRequest("GET", "/users/42")
+	w.Match(req)
+	if req.Param("id") != "42" || len(req.ParamsSlice()) != 1 {
+		t.Fatalf("unexpected params %v", req.Params())
+	}
+
+	if u, _ := w.URL("users.id", map[string]interface{}{"id": 42}, nil); u != "/users/42" {
+		t.Fatalf("unexpected url %s", u)
+	}
+}
+
+func TestRouter_ConstraintsOrder(t *testing.T) {
+	w := router2.NewWrapper()
+	w.Get("/posts/{slug:slug}", func() string { return "ok" }).Name("posts")
+	w.Get("/tags/{tag:tpyo}", func() string { return "ok" }).Name("tags")
+	w.Constraint("slug", router2.Regexp("[a-z-]+"))
+
+	if p := w.Match(NewRequest("GET", "/posts/hello-world")); p.Name() != "posts" {
+		t.Fatalf("constraint registered after route should apply, [%s] giving", p.Name())
+	}
+	if p := w.Match(NewRequest("GET", "/posts/Hello")); p.Name() != "" {
+		t.Fatalf("constraint registered after route should apply, [%s] giving", p.Name())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("unregistered constraint should panic")
+		}
+	}()
+	w.Match(NewRequest("GET", "/tags/tpyo"))
+}
+
+func TestRouter_Domain(t *testing.T) {
+	w := router2.NewWrapper()
+	w.Get("/", func() string { return "ok" }).Name("home")
+	w.Domain("api.example.com", func(w *router2.Wrapper) {
+		w.Get("/", func() string { return "ok" }).Name("api.home")
+	})
+	w.Domain("{tenant}.example.com", func(w *router2.Wrapper) {
+		w.Get("/users/:id", func() string { return "ok" }).Name("tenant.users")
+	})
+
+	cases := map[string]string{
+		"http://api.example.com/":          "api.home",
+		"http://API.example.com:8080/":     "api.home",
+		"http://foo.example.com/users/42":  "tenant.users",
+		"http://foo.example.com/":          "home",
+		"http://other.com/":                "home",
+		"http://foo.bar.example.com/users": "",
+	}
+	for uri, name := range cases {
+		req := tests.NewRequest("GET", uri)
+		if p := w.Match(req); p.Name() != name {
+			t.Fatalf("%s expect route [%s], [%s] giving", uri, name, p.Name())
+		}
+	}
+
+	req := tests.NewRequest("GET", "http://foo.example.com/users/42")
+	w.Match(req)
+	if req.Param("tenant") != "foo" || string(req.ParamsSlice()[0]) != "foo" || req.Param("id") != "42" {
+		t.Fatalf("unexpected params %v", req.Params())
+	}
+
+	u, _ := w.URL("tenant.users", map[string]interface{}{"tenant": "bar", "id": 1}, nil)
+	if u != "//bar.example.com/users/1" {
+		t.Fatalf("unexpected url %s", u)
+	}
+}
+
+func TestWrapper_GroupWith(t *testing.T) {
+	w := router2.NewWrapper()
+	pipe := func(r contracts.RequestContract, next pipeline.PipeHandler) contracts.ResponseContract {
+		return next(r)
+	}
+
+	w.GroupWith(router2.GroupOptions{Prefix: "admin", Middleware: []string{"auth"}, NamePrefix: "admin."}, func(w *router2.Wrapper) {
+		w.GroupWith(router2.GroupOptions{Prefix: "users", Use: []pipeline.PipeFunc{pipe}, NamePrefix: "users."}, func(w *router2.Wrapper) {
+			w.Get("/:id", func() string { return "ok" }).Name("show").Middleware("log")
+		})
+		w.Get("/dashboard", func() string { return "ok" }).Name("dashboard")
+	}).Middleware("admin")
+
+	req := NewRequest("GET", "/admin/users/42")
+	p := w.Match(req)
+	if p.Name() != "admin.users.show" {
+		t.Fatalf("unexpected route name [%s]", p.Name())
+	}
+	if fmt.Sprint(p.Middleware()) != "[auth log admin]" || len(p.PipeFuncs()) != 1 {
+		t.Fatalf("unexpected route middleware %v, pipes %d", p.Middleware(), len(p.PipeFuncs()))
+	}
+
+	p = w.Match(NewRequest("GET", "/admin/dashboard"))
+	if p.Name() != "admin.dashboard" || fmt.Sprint(p.Middleware()) != "[auth admin]" || len(p.PipeFuncs()) != 0 {
+		t.Fatalf("unexpected route %s %v %d", p.Name(), p.Middleware(), len(p.PipeFuncs()))
+	}
+
+	w.Group(func(w *router2.Wrapper) {
+		w.Domain("api.example.com", func(w *router2.Wrapper) {
+			w.Get("/status", func() string { return "ok" }).Name("api.status")
+		})
+	}, "v1")
+	if p = w.Match(tests.NewRequest("GET", "http://api.example.com/v1/status")); p.Name() != "api.status" {
+		t.Fatalf("domain in group should be prefixed, [%s] giving", p.Name())
+	}
+}
+
+type PhotoController struct{}
+
+func (PhotoController) Index() string   { return "index" }
+func (PhotoController) Create() string  { return "create" }
+func (PhotoController) Show() string    { return "show" }
+func (PhotoController) Update() string  { return "update" }
+func (PhotoController) Destroy() string { return "destroy" }
+
+func TestWrapper_Resource(t *testing.T) {
+	w := router2.NewWrapper()
+	w.Resource("photos", PhotoController{}, router2.ResourceOptions{Except: []string{"destroy"}})
+	w.APIResource("users.photos", PhotoController{})
+
+	cases := []struct{ method, path, name string }{
+		{"GET", "/photos", "photos.index"},
+		{"GET", "/photos/create", "photos.create"},
+		{"GET", "/photos/42", "photos.show"},
+		{"PATCH", "/photos/42", "photos.update"},
+		{"DELETE", "/photos/42", ""},
+		{"POST", "/photos", ""},
+		{"GET", "/photos/42/edit", ""},
+		{"GET", "/users/1/photos", "users.photos.index"},
+		{"GET", "/users/1/photos/create", "users.photos.show"},
+		{"DELETE", "/users/1/photos/42", "users.photos.destroy"},
+	}
+	for _, c := range cases {
+		if p := w.Match(NewRequest(c.method, c.path)); p.Name() != c.name {
+			t.Fatalf("[%s] %s expect route [%s], [%s] giving", c.method, c.path, c.name, p.Name())
+		}
+	}
+
+	req := NewRequest("GET", "/users/1/photos/42")
+	w.Match(req)
+	if req.Param("user") != "1" || req.Param("photo") != "42" {
+		t.Fatalf("unexpected params %v", req.Params())
+	}
+}
+
+func TestWrapper_RouteInfos(t *testing.T) {
+	w := router2.NewWrapper()
+	w.Get("/users/:id", func() string { return "ok" }).Name("users.show").Middleware("auth")
+	w.Post("/users", func() string { return "ok" })
+	w.APIResource("photos", PhotoController{})
+
+	infos := w.RouteInfos()
+	if len(infos) == 0 || infos[0].Path != "/photos" || infos[0].Method != "GET" {
+		t.Fatalf("unexpected route infos %v", infos)
+	}
+	for _, info := range infos {
+		if info.Path == "/users/:id" {
+			if info.Name != "users.show" || fmt.Sprint(info.Middleware) != "[auth]" || info.Handler == "" {
+				t.Fatalf("unexpected route info %+v", info)
+			}
+		}
+		if info.Name == "photos.show" && info.Handler != "router_test.PhotoController@Show" {
+			t.Fatalf("unexpected resource handler [%s]", info.Handler)
+		}
+	}
+}
+
+func TestWrapper_Fallback(t *testing.T) {
+	w := router2.NewWrapper()
+	w.Get("/api/users", func() string { return "ok" }).Name("users")
+	w.Fallback(func() string { return "ok" }).Name("root")
+	w.Group(func(w *router2.Wrapper) {
+		w.Fallback(func() string { return "ok" }).Name("spa")
+	}, "app")
+	w.GroupWith(router2.GroupOptions{Prefix: "api", Middleware: []string{"json"}}, func(w *router2.Wrapper) {
+		w.Fallback(func() string { return "ok" }).Name("api")
+	})
+	w.Group(func(w *router2.Wrapper) {
+		w.Fallback(func() string { return "ok" }).Name("api.version")
+	}, "api/{version:int}")
+
+	cases := map[string]string{
+		"/api/users":     "users",
+		"/api/missing":   "api",
+		"/api/2/missing": "api.version",
+		"/app":           "spa",
+		"/app/users/42":  "spa",
+		"/application":   "root",
+		"/":              "root",
+	}
+	for path, name := range cases {
+		if p := w.Match(NewRequest("GET", path)); p.Name() != name {
+			t.Fatalf("fallback of [%s] expect %s, %s giving", path, name, p.Name())
+		}
+	}
+
+	req := NewRequest("GET", "/api/2/missing")
+	w.Match(req)
+	if req.Param("version") != "2" {
+		t.Fatalf("fallback should set prefix params, %v giving", req.Params())
+	}
+	if p := w.Match(NewRequest("GET", "/api/x")); fmt.Sprint(p.Middleware()) != "[json]" {
+		t.Fatalf("fallback should carry group middleware, %v giving", p.Middleware())
+	}
+}
+
+func TestWrapper_Remove(t *testing.T) {
+	w := router2.NewWrapper()
+	w.Get("/users", func() string { return "ok" }).Name("users")
+	w.Get("/users/{id}", func() string { return "ok" }).Name("users.show")
+
+	if p := w.Match(NewRequest("GET", "/users/1")); p.Name() != "users.show" {
+		t.Fatalf("unexpected route [%s]", p.Name())
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			w.Match(NewRequest("GET", "/users"))
+			w.Match(NewRequest("GET", "/flags/1"))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		w.Get("/flags/{id}", func() string { return "ok" })
+		w.Remove(router2.GET, "/flags/{id}")
+	}
+	<-done
+
+	if !w.Remove(router2.GET, "/users/{id}") || w.Remove(router2.GET, "/users/{id}") {
+		t.Fatal("route should be removed once")
+	}
+	if p := w.Match(NewRequest("GET", "/users/1")); p.Name() != "" {
+		t.Fatalf("removed route matched [%s]", p.Name())
+	}
+	if p := w.Match(NewRequest("GET", "/users")); p.Name() != "users" {
+		t.Fatalf("unexpected route [%s]", p.Name())
+	}
+
+	w.Replace(func(w *router2.Wrapper) {
+		w.Get("/posts", func() string { return "ok" }).Name("posts")
+	})
+	if p := w.Match(NewRequest("GET", "/users")); p.Name() != "" {
+		t.Fatalf("replaced route matched [%s]", p.Name())
+	}
+	if p := w.Match(NewRequest("GET", "/posts")); p.Name() != "posts" {
+		t.Fatalf("unexpected route [%s]", p.Name())
+	}
+}
+
+func TestWrapper_HolderWhileServing(t *testing.T) {
+	w := router2.NewWrapper()
+	group := w.Group(func(w *router2.Wrapper) {
+		w.Get("/users/{id:slug}", func() string { return "ok" }).Name("users").Middleware("auth")
+	}, "admin")
+	w.Constraint("slug", router2.Regexp("[a-z]+"))
+	w.Match(NewRequest("GET", "/admin/users/me"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			for _, path := range []string{"/admin/users/me", "/posts/1"} {
+				p := w.Match(NewRequest("GET", path))
+				_, _ = p.Name(), p.Middleware()
+			}
+		}
+	}()
+	w.Constraint("slug", router2.Regexp("[a-z]{2}"))
+	for i := 0; i < 100; i++ {
+		w.Get(fmt.Sprintf("/posts/%d/{id}", i), func() string { return "ok" }).Name("posts").Middleware("auth")
+	}
+	w.Get("/posts/{id}", func() string { return "ok" }).When(func(r contracts.RequestContract) bool {
+		return true
+	}).Name("posts").Middleware("auth")
+	// route of group is replaced by its own holder before
+	group.Middleware("admin")
+	<-done
+
+	p := w.Match(NewRequest("GET", "/admin/users/me"))
+	if ms := p.Middleware(); p.Name() != "users" || len(ms) != 2 || ms[0] != "auth" || ms[1] != "admin" {
+		t.Fatalf("unexpected route [%s] %v", p.Name(), ms)
+	}
+	if p := w.Match(NewRequest("GET", "/posts/1")); p.Name() != "posts" || len(p.Middleware()) != 1 {
+		t.Fatalf("unexpected route [%s] %v", p.Name(), p.Middleware())
+	}
+}
+
+func TestWrapper_Conditions(t *testing.T) {
+	w := router2.NewWrapper()
+	w.Get("/users", func() string { return "ok" }).Name("users.v2").Header("X-Api-Version", "2")
+	w.Get("/users", func() string { return "ok" }).Name("users.json").Accepts("application/json")
+	w.Get("/users", func() string { return "ok" }).Name("users")
+	w.Get("/posts/{id}", func() string { return "ok" }).Name("posts.beta").When(func(r contracts.RequestContract) bool {
+		return r.HeaderString("X-Beta") == "1"
+	})
+
+	cases := []struct {
+		path    string
+		headers map[string]string
+		name    string
+	}{
+		{"/users", map[string]string{"X-Api-Version": "2", "Accept": "application/json"}, "users.v2"},
+		{"/users", map[string]string{"Accept": "text/html, application/*;q=0.9"}, "users.json"},
+		{"/users", map[string]string{"Accept": "text/html"}, "users"},
+		{"/posts/1", map[string]string{"X-Beta": "1"}, "posts.beta"},
+		{"/posts/1", nil, ""},
+	}
+	for _, c := range cases {
+		req := NewRequest("GET", c.path)
+		req.Headers = c.headers
+		if p := w.Match(req); p.Name() != c.name {
+			t.Fatalf("[%s] %v expect %s, %s giving", c.path, c.headers, c.name, p.Name())
+		}
+	}
+
+	w.Post("/x", func() string { return "ok" }).Name("a").Header("X-A", "")
+	w.Post("/x", func() string { return "ok" }).Name("b")
+	w.Get("/x", func() string { return "ok" }).Name("get")
+	w.Remove(router2.GET, "/x")
+	if p := w.Match(NewRequest("POST", "/x")); p.Name() != "b" {
+		t.Fatalf("candidates of other methods should be kept on removing, %s giving", p.Name())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("route after unconditional route should panic")
+		}
+	}()
+	w.Get("/users", func() string { return "ok" })
+}
+
+func TestWrapper_Methods(t *testing.T) {
+	w := router2.NewWrapper()
+	w.Methods([]string{"propfind", "REPORT"}, "/calendars/{user}", func() string { return "ok" }).Name("calendar")
+	w.RegisterAction(router2.TRACE, "/trace", func() string { return "ok" }).Name("trace")
+	w.RegisterAction(router2.ANY, "/any", func() string { return "ok" }).Name("any")
+	w.RegisterAction(router2.ANY, "/any/{id}", func() string { return "ok" }).Name("any.id")
+	w.Get("/any/{id}", func() string { return "ok" }).Name("any.get")
+	w.RegisterAction(router2.GET, "/any/{id:int}", func() string { return "ok" }).Name("any.int")
+	// methods registered after routes of ANY are matched
+	mkcol := router2.RegisterMethod("MKCOL")
+
+	if router2.RegisterMethod("mkcol") != mkcol {
+		t.Fatal("registered method should keep its bit")
+	}
+	cases := []struct{ method, path, name string }{
+		{"PROPFIND", "/calendars/foo", "calendar"},
+		{"REPORT", "/calendars/foo", "calendar"},
+		{"TRACE", "/trace", "trace"},
+		{"MKCOL", "/any", "any"},
+		{"POST", "/any", "any"},
+		{"CONNECT", "/any", ""},
+		{"TRACE", "/any", ""},
+		{"GET", "/any/foo", "any.get"},
+		{"POST", "/any/foo", "any.id"},
+		{"GET", "/any/42", "any.int"},
+	}
+	for _, c := range cases {
+		if p := w.Match(NewRequest(c.method, c.path)); p.Name() != c.name {
+			t.Fatalf("[%s] %s expect %s, %s giving", c.method, c.path, c.name, p.Name())
+		}
+	}
+
+	req := NewRequest("MKCOL", "/calendars/foo")
+	resp := w.Match(req).Handler()(req)
+	if resp.StatusCode() != 405 || !strings.Contains(resp.Headers()["Allow"], "PROPFIND") {
+		t.Fatalf("unexpected response %d %v", resp.StatusCode(), resp.Headers())
+	}
+}
+
+func TestWrapper_DefineRoutes(t *testing.T) {
+	def, e := router2.ParseRouteDefinitions([]byte(`
+prefix: api
+middleware: [json]
+routes:
+  - path: /photos
+    action: photo
+    name: photos
+groups:
+  - prefix: admin
+    name_prefix: admin.
+    middleware: [auth]
+    routes:
+      - path: /photos/{id:int}
+        methods: [put, patch]
+        action: photo@Update
+        name: photos.update
+`), "yaml")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	w := router2.NewWrapper()
+	w.BindController("photo", PhotoController{})
+	if e := w.DefineRoutes(def); e != nil {
+		t.Fatal(e)
+	}
+	if p := w.Match(NewRequest("GET", "/api/photos")); p.Name() != "photos" {
+		t.Fatalf("unexpected route [%s]", p.Name())
+	}
+	p := w.Match(NewRequest("PATCH", "/api/admin/photos/1"))
+	if p.Name() != "admin.photos.update" || fmt.Sprint(p.Middleware()) != "[json auth]" {
+		t.Fatalf("unexpected route [%s] %v", p.Name(), p.Middleware())
+	}
+
+	def, _ = router2.ParseRouteDefinitions([]byte(`{"routes": [
+		{"path": "/a", "action": "missing@Index"},
+		{"path": "/b", "action": "photo@Missing"},
+		{"path": "/c", "action": "photo", "methods": ["FETCH"]}
+	]}`), "json")
+	count := len(w.RouteInfos())
+	e = w.DefineRoutes(def)
+	errs, ok := e.(router2.DefinitionError)
+	if !ok || len(errs) != 3 {
+		t.Fatalf("expect 3 definition errors, %v giving", e)
+	}
+	if len(w.RouteInfos()) != count {
+		t.Fatal("invalid definitions should not be registered")
+	}
+
+	def, _ = router2.ParseRouteDefinitions([]byte(`{"prefix": "api", "routes": [
+		{"path": "/videos", "action": "photo"},
+		{"path": "/photos", "action": "photo"},
+		{"path": "/albums/{id", "action": "photo"},
+		{"path": "/tags", "action": "photo"},
+		{"path": "/tags", "action": "photo"}
+	]}`), "json")
+	e = w.DefineRoutes(def)
+	if errs, ok := e.(router2.DefinitionError); !ok || len(errs) != 3 {
+		t.Fatalf("expect 3 conflict errors, %v giving", e)
+	}
+	if len(w.RouteInfos()) != count {
+		t.Fatal("nothing should be registered if any definition conflicts")
+	}
+	if p := w.Match(NewRequest("GET", "/api/videos")); p.Name() != "" || p.Action() != nil {
+		t.Fatal("route before conflicting one should not be registered")
+	}
+	if _, e := router2.ParseRouteDefinitions([]byte("routes:\n  - paht: /x\n"), "yaml"); e == nil {
+		t.Fatal("unknown field should be rejected")
+	}
+}
+
+func TestRouter_ExtendedSegments(t *testing.T) {
+	w := router2.NewWrapper()
+	w.Get("/files/:name.:ext", func() string { return "ok" }).Name("files")
+	w.Get("/files/readme.txt", func() string { return "ok" }).Name("readme")
+	w.Get("/posts/:year/:month?", func() string { return "ok" }).Name("posts")
+	w.Get("/posts/{year}/{month}/{day:int}", func() string { return "ok" }).Name("posts.day")
+	w.Get("/archive/*path/edit", func() string { return "ok" }).Name("archive.edit")
+	w.Get("/v1/items:batch", func() string { return "ok" }).Name("batch")
+	w.Get("/{lang}/docs/:page.:format", func() string { return "ok" }).Name("docs")
+	w.Get("/en/docs/{page}.{format:[a-z]+}", func() string { return "ok" }).Name("docs.en")
+
+	cases := []struct {
+		path, name string
+		params     map[string]string
+	}{
+		{"/files/archive.tar.gz", "files", map[string]string{"name": "archive.tar", "ext": "gz"}},
+		{"/files/readme.txt", "readme", nil},
+		{"/files/readme", "", nil},
+		{"/posts/2024", "posts", map[string]string{"year": "2024"}},
+		{"/posts/2024/05", "posts", map[string]string{"year": "2024", "month": "05"}},
+		{"/posts/2024/05/01", "posts.day", map[string]string{"day": "01"}},
+		{"/archive/a/b/edit", "archive.edit", map[string]string{"path": "/a/b"}},
+		{"/archive/edit", "", nil},
+		{"/v1/items:batch", "batch", nil},
+		{"/en/docs/intro.html", "docs.en", map[string]string{"page": "intro"}},
+		{"/en/docs/intro.2", "docs", map[string]string{"lang": "en", "format": "2"}},
+		{"/fr/docs/intro.html", "docs", map[string]string{"lang": "fr"}},
+	}
+	for _, c := range cases {
+		req := NewRequest("GET", c.path)
+		if p := w.Match(req); p.Name() != c.name {
+			t.Fatalf("[%s] expect %s, %s giving", c.path, c.name, p.Name())
+		}
+		for k, v := range c.params {
+			if req.Param(k) != v {
+				t.Fatalf("[%s] param %s expect %s, %s giving", c.path, k, v, req.Param(k))
+			}
+		}
+	}
+
+	if u, _ := w.URL("posts", map[string]interface{}{"year": 2024}, nil); u != "/posts/2024" {
+		t.Fatalf("unexpected url %s", u)
+	}
+	if u, _ := w.URL("files", map[string]interface{}{"name": "a", "ext": "md"}, nil); u != "/files/a.md" {
+		t.Fatalf("unexpected url %s", u)
+	}
+
+	for _, path := range []string{"/posts/:y", "/files/{a}{b}", "/x/:a?/b"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("[%s] should panic at registering", path)
+				}
+			}()
+			w.Get(path, func() string { return "ok" })
+		}()
+	}
+}
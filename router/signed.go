@@ -0,0 +1,165 @@
+package router
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/enorith/http/content"
+	"github.com/enorith/http/contracts"
+	httpErrors "github.com/enorith/http/errors"
+	"github.com/enorith/http/pipeline"
+)
+
+const (
+	//SignatureKey query key of signature
+	SignatureKey = "signature"
+	//ExpiresKey query key of expiry, unix timestamp
+	ExpiresKey = "expires"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrSignatureExpired = errors.New("signature expired")
+)
+
+//Signer sign urls with HMAC-SHA256, signing with the first key,
+// verifying with any of keys for key rotation
+type Signer struct {
+	keys [][]byte
+}
+
+//Sign path and query of url, host is signed as well if given, eg: url of domain route.
+// Expires is ignored if zero
+func (s *Signer) Sign(rawURL string, expires time.Time) (string, error) {
+	if len(s.keys) == 0 {
+		return "", errors.New("signer: no key configured")
+	}
+
+	u, e := url.Parse(rawURL)
+	if e != nil {
+		return "", e
+	}
+	query := u.Query()
+	query.Del(SignatureKey)
+	if !expires.IsZero() {
+		query.Set(ExpiresKey, strconv.FormatInt(expires.Unix(), 10))
+	}
+	query.Set(SignatureKey, sign(s.keys[0], u.Hostname(), u.Path, query))
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+//Verify signature and expiry of url, url with host is verified against signature bound to the host
+func (s *Signer) Verify(u *url.URL, now time.Time) error {
+	query := u.Query()
+	signature := query.Get(SignatureKey)
+	if signature == "" {
+		return ErrInvalidSignature
+	}
+	query.Del(SignatureKey)
+
+	valid := false
+	for _, key := range s.keys {
+		if hmac.Equal([]byte(signature), []byte(sign(key, u.Hostname(), u.Path, query))) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+
+	if expires := query.Get(ExpiresKey); expires != "" {
+		ts, e := strconv.ParseInt(expires, 10, 64)
+		if e != nil {
+			return ErrInvalidSignature
+		}
+		if now.Unix() > ts {
+			return ErrSignatureExpired
+		}
+	}
+
+	return nil
+}
+
+//sign host and path with sorted query, signature excluded
+func sign(key []byte, host, path string, query url.Values) string {
+	mac := hmac.New(sha256.New, key)
+	if host != "" {
+		mac.Write([]byte("//" + strings.ToLower(host)))
+	}
+	mac.Write([]byte(path))
+	mac.Write([]byte{'?'})
+	mac.Write([]byte(query.Encode()))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func NewSigner(keys ...[]byte) *Signer {
+	return &Signer{keys: keys}
+}
+
+//SigningKeys set keys of url signer, the first is used to sign, all of them are accepted
+func (w *Wrapper) SigningKeys(keys ...[]byte) *Wrapper {
+	w.reg.signer.Store(NewSigner(keys...))
+	return w
+}
+
+//Signer url signer of router
+func (w *Wrapper) Signer() *Signer {
+	return w.reg.signer.Load().(*Signer)
+}
+
+//SignedURL url of named route with signature, expired after ttl, never expires if ttl is zero
+// 	w.SignedURL("unsubscribe", map[string]interface{}{"user": 42}, 24*time.Hour)
+//
+func (w *Wrapper) SignedURL(name string, params map[string]interface{}, ttl time.Duration, query ...url.Values) (string, error) {
+	var q url.Values
+	if len(query) > 0 {
+		q = query[0]
+	}
+
+	u, e := w.URL(name, params, q)
+	if e != nil {
+		return "", e
+	}
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+
+	u, e = w.Signer().Sign(u, expires)
+	if e != nil {
+		return "", fmt.Errorf("route [%s]: %s", name, e)
+	}
+
+	return u, nil
+}
+
+//SignedMiddleware responses 403 for invalid or expired signed url, url of domain route
+// signed for another host is invalid,
+// resolved as "signed" middleware by kernel
+func SignedMiddleware(w *Wrapper) pipeline.RequestMiddleware {
+	return pipeline.FuncMiddleware{HandleFunc: func(r contracts.RequestContract, next pipeline.PipeHandler) contracts.ResponseContract {
+		// signature of domain route is bound to host, other routes are verified by path
+		u := *r.GetURL()
+		u.Host = ""
+		if route := w.RouteByName(r.GetRouteName()); route != nil && route.domain != nil {
+			u.Host = hostOf(r)
+		}
+		if e := w.Signer().Verify(&u, time.Now()); e != nil {
+			return content.ErrResponseFromError(httpErrors.AccessDenied(e.Error()), 403, nil)
+		}
+
+		return next(r)
+	}}
+}
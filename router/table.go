@@ -0,0 +1,529 @@
+package router
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/enorith/http/contracts"
+)
+
+type methodTrees struct {
+	mu    *sync.RWMutex
+	nodes map[string]*node
+}
+
+func (mt *methodTrees) get(method string) *node {
+	mt.mu.RLock()
+	defer mt.mu.RUnlock()
+
+	return mt.nodes[method]
+}
+
+func (mt *methodTrees) set(method string, n *node) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	mt.nodes[method] = n
+}
+
+//routeTable routes of default table or a domain
+type routeTable struct {
+	routes   map[string][]*ParamRoute
+	trees    *methodTrees
+	patterns map[string][]*ParamRoute
+	index    map[string]*patternIndex
+	// fallbacks of group prefixes, longest first
+	fallbacks []*ParamRoute
+	// routes sharing method and path, keyed by first registered one
+	candidates map[*ParamRoute][]*ParamRoute
+}
+
+//lookup route of method, routes of ANY take precedence only if more specific
+func (t *routeTable) lookup(method, path string) routeValue {
+	value := t.lookupMethod(method, path)
+	if t.hasAny() && matchesAny(method) {
+		if v := t.lookupMethod(AnyMethod, path); v.route != nil && (value.route == nil || compareRank(v.route.rank, value.route.rank) > 0) {
+			return v
+		}
+	}
+
+	return value
+}
+
+//lookupMethod route of method, from tree and patterns which are more specific
+func (t *routeTable) lookupMethod(method, path string) (value routeValue) {
+	if tree := t.trees.get(method); tree != nil {
+		value = tree.getValue(path)
+	}
+	if v, ok := t.matchPatterns(method, path, value.route); ok {
+		return v
+	}
+
+	return
+}
+
+//hasAny whether routes registered with ANY
+func (t *routeTable) hasAny() bool {
+	return len(t.routes[AnyMethod]) > 0
+}
+
+//matchPattern whether path is matched by patterns of method
+func (t *routeTable) matchPattern(method, path string) bool {
+	_, ok := t.matchPatterns(method, path, nil)
+
+	return ok
+}
+
+//matchPatterns first pattern matching path in rank order, more specific than route if not nil.
+// Only patterns of the first segment literal and patterns starting with parameter are matched
+func (t *routeTable) matchPatterns(method, path string, route *ParamRoute) (routeValue, bool) {
+	idx := t.index[method]
+	if idx == nil {
+		return routeValue{}, false
+	}
+
+	first := strings.TrimPrefix(path, "/")
+	if i := strings.IndexByte(first, '/'); i > -1 {
+		first = first[:i]
+	}
+	static, dynamic := idx.static[first], idx.dynamic
+	for len(static) > 0 || len(dynamic) > 0 {
+		// static first segment ranks higher, merged in rank order
+		var p *ParamRoute
+		if len(dynamic) == 0 || (len(static) > 0 && compareRank(static[0].rank, dynamic[0].rank) >= 0) {
+			p, static = static[0], static[1:]
+		} else {
+			p, dynamic = dynamic[0], dynamic[1:]
+		}
+		if route != nil && compareRank(p.rank, route.rank) <= 0 {
+			break
+		}
+
+		if params, paramsSlice, ok := p.pattern.match(path); ok {
+			return routeValue{route: p, params: params, paramsSlice: paramsSlice}, true
+		}
+	}
+
+	return routeValue{}, false
+}
+
+//patternIndex patterns of method in rank order, by literal of first segment
+type patternIndex struct {
+	static map[string][]*ParamRoute
+	// patterns starting with parameter
+	dynamic []*ParamRoute
+}
+
+//indexPatterns rebuild index of patterns of method
+func (t *routeTable) indexPatterns(method string) {
+	routes := t.patterns[method]
+	if len(routes) == 0 {
+		delete(t.index, method)
+		return
+	}
+
+	idx := &patternIndex{static: make(map[string][]*ParamRoute)}
+	for _, route := range routes {
+		if first := route.pattern.tokens[0]; !first.isParam() && first.parts == nil {
+			idx.static[first.literal] = append(idx.static[first.literal], route)
+		} else {
+			idx.dynamic = append(idx.dynamic, route)
+		}
+	}
+	t.index[method] = idx
+}
+
+//clone table, trees are rebuilt since nodes are modified in place
+func (t *routeTable) clone() *routeTable {
+	c := newRouteTable()
+	for method, routes := range t.routes {
+		c.routes[method] = append([]*ParamRoute(nil), routes...)
+		c.buildTree(method)
+	}
+	for method, routes := range t.patterns {
+		c.patterns[method] = append([]*ParamRoute(nil), routes...)
+		c.indexPatterns(method)
+	}
+	c.fallbacks = append([]*ParamRoute(nil), t.fallbacks...)
+	for head, routes := range t.candidates {
+		c.candidates[head] = routes
+	}
+
+	return c
+}
+
+//buildTree build tree of method from routes without brace parameters
+func (t *routeTable) buildTree(method string) {
+	var tree *node
+	for _, route := range t.routes[method] {
+		if route.pattern != nil || route.head != nil {
+			continue
+		}
+		if tree == nil {
+			tree = new(node)
+		}
+		tree.addRoute(route.path, route)
+	}
+
+	t.trees.set(method, tree)
+}
+
+//remove routes of method and path, returns whether any removed
+func (t *routeTable) remove(method, path string) bool {
+	routes := removeRoutes(t.routes[method], path)
+	if len(routes) == len(t.routes[method]) {
+		return false
+	}
+
+	for _, route := range t.routes[method] {
+		// candidates are keyed by head of method, routes of other methods are kept
+		if route.path == path {
+			delete(t.candidates, route)
+		}
+	}
+	t.routes[method] = routes
+	t.patterns[method] = removeRoutes(t.patterns[method], path)
+	t.indexPatterns(method)
+	t.buildTree(method)
+
+	return true
+}
+
+//replace route with copy of it, trees and index of methods are rebuilt
+func (t *routeTable) replace(old, route *ParamRoute) {
+	for method, routes := range t.routes {
+		i := indexOfRoute(routes, old)
+		if i < 0 {
+			continue
+		}
+		routes[i] = route
+		if j := indexOfRoute(t.patterns[method], old); j > -1 {
+			t.patterns[method][j] = route
+			t.indexPatterns(method)
+		} else {
+			t.buildTree(method)
+		}
+	}
+
+	if candidates, ok := t.candidates[old]; ok {
+		delete(t.candidates, old)
+		t.candidates[route] = candidates
+	}
+	for head, candidates := range t.candidates {
+		if i := indexOfRoute(candidates, old); i > -1 {
+			// slices are shared by copies of table
+			candidates = append([]*ParamRoute(nil), candidates...)
+			candidates[i] = route
+			t.candidates[head] = candidates
+		}
+	}
+	if i := indexOfRoute(t.fallbacks, old); i > -1 {
+		t.fallbacks[i] = route
+	}
+}
+
+func indexOfRoute(routes []*ParamRoute, route *ParamRoute) int {
+	for i, r := range routes {
+		if r == route {
+			return i
+		}
+	}
+
+	return -1
+}
+
+//headOf first route registered with method and path
+func (t *routeTable) headOf(method, path string) *ParamRoute {
+	for _, route := range t.routes[method] {
+		if route.path == path && route.head == nil {
+			return route
+		}
+	}
+
+	return nil
+}
+
+//addCandidate add route sharing path with head, routes before it should have conditions
+func (t *routeTable) addCandidate(method string, head, route *ParamRoute) {
+	candidates, ok := t.candidates[head]
+	if !ok {
+		candidates = []*ParamRoute{head}
+	}
+	for _, c := range candidates {
+		if len(c.conditions) == 0 {
+			panic("a route is already registered for path '" + route.path + "'")
+		}
+	}
+
+	route.head, route.pattern, route.rank = head, head.pattern, head.rank
+	t.routes[method] = append(t.routes[method], route)
+	// slices are shared by copies of table
+	t.candidates[head] = append(candidates[:len(candidates):len(candidates)], route)
+}
+
+//choose first route of candidates whose conditions hold, nil if none
+func (t *routeTable) choose(route *ParamRoute, r contracts.RequestContract) *ParamRoute {
+	candidates, ok := t.candidates[route]
+	if !ok {
+		candidates = []*ParamRoute{route}
+	}
+	for _, c := range candidates {
+		if c.holds(r) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func removeRoutes(routes []*ParamRoute, path string) []*ParamRoute {
+	var rest []*ParamRoute
+	for _, route := range routes {
+		if route.path != path {
+			rest = append(rest, route)
+		}
+	}
+
+	return rest
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{
+		routes: func() map[string][]*ParamRoute {
+			rs := map[string][]*ParamRoute{}
+			for _, v := range methodsOf(ANY) {
+				rs[v] = []*ParamRoute{}
+			}
+
+			return rs
+		}(),
+		trees: &methodTrees{
+			mu:    &sync.RWMutex{},
+			nodes: make(map[string]*node),
+		},
+		patterns:   make(map[string][]*ParamRoute),
+		index:      make(map[string]*patternIndex),
+		candidates: make(map[*ParamRoute][]*ParamRoute),
+	}
+}
+
+type domainTable struct {
+	*domain
+	table *routeTable
+}
+
+//routeSet default table and tables of domains, matched as a whole
+type routeSet struct {
+	table   *routeTable
+	domains []domainTable
+	// scope of default table, matched without allocating if no domains
+	defaults []scope
+}
+
+//tables default table and tables of domains
+func (s *routeSet) tables() []*routeTable {
+	tables := []*routeTable{s.table}
+	for _, d := range s.domains {
+		tables = append(tables, d.table)
+	}
+
+	return tables
+}
+
+//tableOf table of domain, created if not exists
+func (s *routeSet) tableOf(d *domain) *routeTable {
+	if d == nil {
+		return s.table
+	}
+	for _, dt := range s.domains {
+		if dt.host == d.host {
+			return dt.table
+		}
+	}
+
+	domains := append(s.domains, domainTable{domain: d, table: newRouteTable()})
+	// more specific domain first, keep registration order of same rank
+	for i := len(domains) - 1; i > 0 && compareRank(domains[i].rank, domains[i-1].rank) > 0; i-- {
+		domains[i], domains[i-1] = domains[i-1], domains[i]
+	}
+	s.domains = domains
+
+	return s.tableOf(d)
+}
+
+func (s *routeSet) clone() *routeSet {
+	c := newRouteSetOf(s.table.clone())
+	for _, d := range s.domains {
+		c.domains = append(c.domains, domainTable{domain: d.domain, table: d.table.clone()})
+	}
+
+	return c
+}
+
+func newRouteSet() *routeSet {
+	return newRouteSetOf(newRouteTable())
+}
+
+func newRouteSetOf(t *routeTable) *routeSet {
+	return &routeSet{table: t, defaults: []scope{{table: t}}}
+}
+
+//registry shared by router and its groups.
+// Routes are modified in place until the first request matched,
+// after that, modified on a copy which replaces the matching one atomically
+type registry struct {
+	mu      sync.Mutex
+	set     atomic.Value
+	serving int32
+
+	constraints *namedConstraints
+	models      *modelBindings
+	// *Signer, replaced while serving
+	signer *atomic.Value
+}
+
+//routes current route set
+func (reg *registry) routes() *routeSet {
+	return reg.set.Load().(*routeSet)
+}
+
+//serve route set for matching, routes are copied on write since then
+func (reg *registry) serve() *routeSet {
+	if atomic.LoadInt32(&reg.serving) == 0 {
+		// wait for writing in place
+		reg.mu.Lock()
+		atomic.StoreInt32(&reg.serving, 1)
+		reg.mu.Unlock()
+	}
+
+	return reg.routes()
+}
+
+//write modify routes, panic in fn discards the copy
+func (reg *registry) write(fn func(set *routeSet)) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	set := reg.routes()
+	if atomic.LoadInt32(&reg.serving) == 0 {
+		fn(set)
+		return
+	}
+
+	set = set.clone()
+	fn(set)
+	reg.set.Store(set)
+}
+
+//namedConstraints constraints registered by name, copied on write since read at matching
+type namedConstraints struct {
+	mu sync.Mutex
+	m  atomic.Value
+}
+
+func (nc *namedConstraints) get(name string) (Constraint, bool) {
+	c, ok := nc.m.Load().(map[string]Constraint)[name]
+
+	return c, ok
+}
+
+func (nc *namedConstraints) set(name string, c Constraint) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	old := nc.m.Load().(map[string]Constraint)
+	m := make(map[string]Constraint, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	m[name] = c
+	nc.m.Store(m)
+}
+
+func newNamedConstraints() *namedConstraints {
+	nc := &namedConstraints{}
+	nc.m.Store(map[string]Constraint{})
+
+	return nc
+}
+
+//modelBindings bindings of model types, copied on write since read at matching
+type modelBindings struct {
+	mu sync.Mutex
+	m  atomic.Value
+}
+
+func (mb *modelBindings) load() map[reflect.Type]modelBinding {
+	return mb.m.Load().(map[reflect.Type]modelBinding)
+}
+
+func (mb *modelBindings) set(typ reflect.Type, binding modelBinding) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	old := mb.load()
+	m := make(map[reflect.Type]modelBinding, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	m[typ] = binding
+	mb.m.Store(m)
+}
+
+func newModelBindings() *modelBindings {
+	mb := &modelBindings{}
+	mb.m.Store(map[reflect.Type]modelBinding{})
+
+	return mb
+}
+
+//modify routes in place until serving, after that, copies of routes are modified and replace them
+// in copy of route set, routes being matched are never modified
+func (reg *registry) modify(routes []*ParamRoute, fn func(route *ParamRoute)) {
+	reg.write(func(set *routeSet) {
+		for i, route := range routes {
+			// route may be replaced by another holder
+			for route.replaced != nil {
+				route = route.replaced
+			}
+			if atomic.LoadInt32(&reg.serving) == 0 {
+				fn(route)
+				routes[i] = route
+				continue
+			}
+
+			c := route.clone()
+			fn(c)
+			for _, t := range set.tables() {
+				t.replace(route, c)
+			}
+			route.replaced, routes[i] = c, c
+		}
+	})
+}
+
+//stage registry of set sharing constraints, models and signer of reg
+func (reg *registry) stage(set *routeSet) *registry {
+	staged := &registry{
+		constraints: reg.constraints,
+		models:      reg.models,
+		signer:      reg.signer,
+	}
+	staged.set.Store(set)
+
+	return staged
+}
+
+func newRegistry() *registry {
+	reg := &registry{
+		constraints: newNamedConstraints(),
+		models:      newModelBindings(),
+		signer:      &atomic.Value{},
+	}
+	reg.set.Store(newRouteSet())
+	reg.signer.Store(NewSigner())
+
+	return reg
+}
@@ -0,0 +1,126 @@
+package router
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+)
+
+//URL generate url of named route, params fill ":param" and "*catchAll" segments
+func (w *Wrapper) URL(name string, params map[string]interface{}, query url.Values) (string, error) {
+	route := w.RouteByName(name)
+	if route == nil {
+		return "", fmt.Errorf("route [%s] not defined", name)
+	}
+
+	path, e := BuildPath(route.path, params)
+	if e != nil {
+		return "", fmt.Errorf("route [%s]: %s", name, e)
+	}
+
+	if route.domain != nil {
+		host, e := buildSegments(route.domain.host, ".", params)
+		if e != nil {
+			return "", fmt.Errorf("route [%s]: %s", name, e)
+		}
+		// scheme relative url of domain route
+		path = "//" + host + path
+	}
+
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	return path, nil
+}
+
+//FuncMap template functions for url generation
+// 	{{ route "users.show" "id" 42 }}
+//
+func (w *Wrapper) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"route": func(name string, pairs ...interface{}) (string, error) {
+			if len(pairs)%2 != 0 {
+				return "", fmt.Errorf("route [%s]: params expect key-value pairs", name)
+			}
+			params := make(map[string]interface{}, len(pairs)/2)
+			for i := 0; i < len(pairs); i += 2 {
+				params[fmt.Sprint(pairs[i])] = pairs[i+1]
+			}
+
+			return w.URL(name, params, nil)
+		},
+	}
+}
+
+//RouteByName find route by name, nil if not exists
+func (r *router) RouteByName(name string) *ParamRoute {
+	if name == "" {
+		return nil
+	}
+
+	for _, t := range r.reg.routes().tables() {
+		for _, m := range append(methodsOf(ANY), AnyMethod) {
+			for _, route := range t.routes[m] {
+				if route.name == name {
+					return route
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+//BuildPath replace parameters of route path
+func BuildPath(path string, params map[string]interface{}) (string, error) {
+	return buildSegments(path, "/", params)
+}
+
+func buildSegments(path, sep string, params map[string]interface{}) (string, error) {
+	segments := strings.Split(path, sep)
+	for i, segment := range segments {
+		var tokens []token
+		if len(segment) > 1 && segment[0] == '*' {
+			tokens = []token{{name: segment[1:], catchAll: true}}
+		} else if ts, e := segmentTokens(segment); e == nil {
+			tokens = ts
+		}
+
+		var b strings.Builder
+		for _, t := range tokens {
+			if !t.isParam() {
+				b.WriteString(t.literal)
+				continue
+			}
+
+			v, ok := params[t.name]
+			if !ok && t.optional {
+				// optional segments are trailing, drop the rest
+				if built := strings.Join(segments[:i], sep); built != "" {
+					return built, nil
+				}
+				return sep, nil
+			}
+			if !ok {
+				return "", fmt.Errorf("missing param [%s]", t.name)
+			}
+
+			if t.catchAll {
+				parts := strings.Split(strings.TrimPrefix(fmt.Sprint(v), "/"), "/")
+				for j, p := range parts {
+					parts[j] = url.PathEscape(p)
+				}
+				b.WriteString(strings.Join(parts, "/"))
+			} else {
+				b.WriteString(url.PathEscape(fmt.Sprint(v)))
+			}
+		}
+		if len(tokens) > 0 {
+			segments[i] = b.String()
+		}
+	}
+
+	return strings.Join(segments, sep), nil
+}
@@ -8,12 +8,13 @@ import (
 	"net/http"
 	"reflect"
 	"strings"
-	"sync"
+	"sync/atomic"
 
 	"github.com/enorith/container"
 	"github.com/enorith/exception"
 	"github.com/enorith/http/content"
 	"github.com/enorith/http/contracts"
+	"github.com/enorith/http/pipeline"
 )
 
 type ContainerRegister func(request contracts.RequestContract) container.Interface
@@ -88,7 +89,19 @@ func (w *Wrapper) RegisterAction(method int, path string, handler interface{}) *
 		routeHandler = invalidHandler(e)
 	}
 
-	return w.Register(method, path, routeHandler)
+	return w.Register(method, path, routeHandler).action(w.actionOf(handler))
+}
+
+//actionOf original handler, controller method for "controller@Method"
+func (w *Wrapper) actionOf(handler interface{}) interface{} {
+	if t, ok := handler.(string); ok {
+		name, method := w.parseController(t)
+		if controller, exists := w.controllers[name]; exists {
+			return ControllerAction{Controller: controller, Method: method}
+		}
+	}
+
+	return handler
 }
 
 func (w *Wrapper) Get(path string, handler interface{}) *routesHolder {
@@ -111,18 +124,108 @@ func (w *Wrapper) Delete(path string, handler interface{}) *routesHolder {
 	return w.RegisterAction(DELETE, path, handler)
 }
 
-func (w *Wrapper) Group(g GroupHandler, prefix ...string) *routesHolder {
-	tr := NewWrapper(prefix...)
-	g(tr)
+//Methods register route of methods, methods not registered are registered by RegisterMethod
+// 	w.Methods([]string{"PROPFIND", "REPORT"}, "/calendars/{user}", handler)
+//
+func (w *Wrapper) Methods(methods []string, path string, handler interface{}) *routesHolder {
+	bits := 0
+	for _, m := range methods {
+		bits |= RegisterMethod(m)
+	}
+
+	return w.RegisterAction(bits, path, handler)
+}
+
+//Constraint register named constraint of route parameter, names are resolved at matching,
+// so routes may be registered before their constraints
+// 	w.Constraint("slug", router.Regexp("[a-z-]+"))
+// 	w.Get("/posts/{slug:slug}", handler)
+//
+func (w *Wrapper) Constraint(name string, c Constraint) *Wrapper {
+	w.reg.constraints.set(name, c)
+	return w
+}
+
+//GroupOptions attributes of route group, composed with attributes of parent groups
+type GroupOptions struct {
+	Prefix     string
+	Middleware []string
+	Use        []pipeline.PipeFunc
+	NamePrefix string
+	Domain     string
+}
+
+//Domain register routes matched by request host, host params are injected before path params.
+// Requests of unmatched hosts fallback to default routes
+// 	w.Domain("{tenant}.example.com", func(w *router.Wrapper) {
+// 		w.Get("/", func(tenant content.Param) string { ... })
+// 	})
+//
+func (w *Wrapper) Domain(host string, g GroupHandler) *routesHolder {
+	return w.GroupWith(GroupOptions{Domain: host}, g)
+}
+
+//GroupWith register group of routes with attributes
+// 	w.GroupWith(router.GroupOptions{Prefix: "admin", Middleware: []string{"auth"}, NamePrefix: "admin."}, func(w *router.Wrapper) {
+// 		w.Get("users", handler).Name("users") // GET /admin/users named "admin.users"
+// 	})
+//
+func (w *Wrapper) GroupWith(opts GroupOptions, g GroupHandler) *routesHolder {
+	gw := w.child()
+	if opts.Domain != "" {
+		gw.domain = w.newDomain(strings.ToLower(opts.Domain))
+	}
+	if opts.Prefix != "" {
+		gw.prefix = JoinPaths(w.prefix, opts.Prefix)
+	}
+	gw.middleware = append(append([]string(nil), w.middleware...), opts.Middleware...)
+	gw.pipeFuncs = append(append([]pipeline.PipeFunc(nil), w.pipeFuncs...), opts.Use...)
+	gw.namePrefix = w.namePrefix + opts.NamePrefix
 
 	var rs []*ParamRoute
-	for method, routes := range tr.routes {
-		for _, p := range routes {
-			rs = append(rs, w.addRoute(method, p.path, p.handler).SetMiddleware(p.middleware))
-		}
+	gw.collectors = append(append([]*[]*ParamRoute(nil), w.collectors...), &rs)
+	g(gw)
+
+	return &routesHolder{routes: rs, reg: w.reg}
+}
+
+//Replace routes of router as a whole, routes registered in g are swapped in atomically,
+// requests in flight keep matching previous routes
+// 	w.Replace(func(w *router.Wrapper) {
+// 		w.Get("/", handler)
+// 		w.Group(plugin.Routes, "plugin")
+// 	})
+//
+func (w *Wrapper) Replace(g GroupHandler) {
+	reg := w.reg.stage(newRouteSet())
+
+	staged := w.child()
+	staged.reg = reg
+	staged.domain, staged.prefix = nil, ""
+	staged.middleware, staged.pipeFuncs, staged.namePrefix, staged.collectors = nil, nil, "", nil
+	g(staged)
+
+	w.reg.mu.Lock()
+	w.reg.set.Store(reg.routes())
+	w.reg.mu.Unlock()
+	// routes of staged wrapper are being served, modify on copy
+	atomic.StoreInt32(&reg.serving, 1)
+}
+
+//child wrapper shares registry with w
+func (w *Wrapper) child() *Wrapper {
+	r := *w.router
+
+	return &Wrapper{router: &r, controllers: w.controllers, ResultHandler: w.ResultHandler}
+}
+
+func (w *Wrapper) Group(g GroupHandler, prefix ...string) *routesHolder {
+	var opts GroupOptions
+	if len(prefix) > 0 {
+		opts.Prefix = prefix[0]
 	}
 
-	return &routesHolder{routes: rs}
+	return w.GroupWith(opts, g)
 }
 
 func (w *Wrapper) FastHttpFileServer(path, root string, stripSlashes int) *routesHolder {
@@ -161,11 +264,7 @@ func (w *Wrapper) wrap(handler interface{}) (RouteHandler, error) {
 		if !exists {
 			return nil, fmt.Errorf("router controller [%s] not registered", name)
 		}
-		return func(req contracts.RequestContract) contracts.ResponseContract {
-			runtime := req.GetContainer()
-			val, err := runtime.MethodCall(controller, method)
-			return w.handleResult(val, err)
-		}, nil
+		return w.controllerHandler(controller, method), nil
 	} else if reflect.TypeOf(handler).Kind() == reflect.Func { // function
 		return func(req contracts.RequestContract) contracts.ResponseContract {
 			runtime := req.GetContainer()
@@ -177,6 +276,14 @@ func (w *Wrapper) wrap(handler interface{}) (RouteHandler, error) {
 	return nil, fmt.Errorf("router handler expect string or func, %s giving", reflect.TypeOf(handler).Kind())
 }
 
+func (w *Wrapper) controllerHandler(controller interface{}, method string) RouteHandler {
+	return func(req contracts.RequestContract) contracts.ResponseContract {
+		runtime := req.GetContainer()
+		val, err := runtime.MethodCall(controller, method)
+		return w.handleResult(val, err)
+	}
+}
+
 func (w *Wrapper) handleResult(val []reflect.Value, err error) contracts.ResponseContract {
 
 	if w.ResultHandler == nil {
@@ -251,20 +358,11 @@ func NewWrapper(ps ...string) *Wrapper {
 		prefix = ps[0]
 	}
 	r := &router{
-		routes: func() map[string][]*ParamRoute {
-			rs := map[string][]*ParamRoute{}
-			for _, v := range methodMap {
-				rs[v] = []*ParamRoute{}
-			}
-
-			return rs
-		}(),
-		trees: &methodTrees{
-			mu:    &sync.RWMutex{},
-			nodes: make(map[string]*node),
-		},
-		prefix: prefix,
+		reg:                    newRegistry(),
+		prefix:                 prefix,
+		HandleMethodNotAllowed: true,
+		HandleOPTIONS:          true,
 	}
 
-	return &Wrapper{router: r}
+	return &Wrapper{router: r, controllers: make(map[string]interface{})}
 }
@@ -2,112 +2,387 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	nt "net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/enorith/http/router"
 	"github.com/valyala/fasthttp"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
+//defaults of ServerConfig
 var (
 	ReadTimeout  = time.Second * 30
 	WriteTimeout = time.Second * 30
 	IdleTimeout  = time.Second * 10
+	// ShutdownTimeout waiting for connections when context of Start done
+	ShutdownTimeout = time.Second * 5
 )
 
 type RouterRegister func(rw *router.Wrapper, k *Kernel)
 
 type Server struct {
 	k *Kernel
+	// Config of server, zero values fallback to defaults
+	Config ServerConfig
+
+	mu      sync.Mutex
+	started bool
+	closed  bool
+	addrs   []net.Addr
+	// listeners and addresses of bindings, passed to process restarted
+	listeners []net.Listener
+	bindAddrs []string
+	ready     chan struct{}
+	readyOnce sync.Once
+	shutdown  func(ctx context.Context) error
 }
 
-func (s *Server) Serve(addr string, register RouterRegister) {
+//Serve register routes and serve at addr until interrupted by SIGINT or SIGTERM
+func (s *Server) Serve(addr string, register RouterRegister) error {
 	register(s.k.Wrapper(), s.k)
 
-	if s.k.Handler == HandlerFastHttp {
-		s.serveFastHttp(addr)
-	} else if s.k.Handler == HandlerNetHttp {
-		s.serveNetHttp(addr)
+	ctx, stop := ShutdownOnSignal(context.Background())
+	defer stop()
+
+	return s.Start(ctx, addr)
+}
+
+//Start listen addr and serve until ctx done or Shutdown called, blocks until server stopped.
+// Server is shut down gracefully in ShutdownTimeout when ctx done
+// 	go s.Start(ctx, ":8000")
+// 	<-s.Ready()
+//
+func (s *Server) Start(ctx context.Context, addr string) error {
+	return s.start(ctx, addr, nil)
+}
+
+//ServeTLS register routes and serve TLS at addr until interrupted by SIGINT or SIGTERM,
+// certificate is reloaded on SIGHUP or files changed
+func (s *Server) ServeTLS(addr, certFile, keyFile string, register RouterRegister) error {
+	register(s.k.Wrapper(), s.k)
+
+	cr, e := NewCertReloader(certFile, keyFile)
+	if e != nil {
+		return e
 	}
+	ctx, stop := ShutdownOnSignal(context.Background())
+	defer stop()
+	go cr.Watch(ctx)
+
+	return s.StartTLS(ctx, addr, &tls.Config{GetCertificate: cr.GetCertificate})
+}
+
+//StartTLS like Start, serve TLS with certificates or GetCertificate of config,
+// HTTP/2 is negotiated on net/http backend
+func (s *Server) StartTLS(ctx context.Context, addr string, config *tls.Config) error {
+	if config == nil || (len(config.Certificates) == 0 && config.GetCertificate == nil) {
+		return errors.New("http: tls config without certificate")
+	}
+
+	return s.start(ctx, addr, config)
+}
+
+func (s *Server) start(ctx context.Context, addr string, config *tls.Config) error {
+	return s.StartBindings(ctx, Binding{Addr: addr, TLS: config})
+}
+
+//ServeListener serve pre-opened listener until ctx done or Shutdown called
+func (s *Server) ServeListener(ctx context.Context, ln net.Listener) error {
+	return s.StartBindings(ctx, Binding{Listener: ln})
+}
+
+//StartBindings serve all bindings until ctx done or Shutdown called, blocks until all stopped.
+// Listeners opened are closed if any binding fails to listen
+// 	s.StartBindings(ctx,
+// 		http.Binding{Addr: "unix:/run/app.sock"},
+// 		http.Binding{Addr: "127.0.0.1:9000", Kernel: admin},
+// 	)
+//
+func (s *Server) StartBindings(ctx context.Context, bindings ...Binding) error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return errors.New("http: server already started")
+	}
+	s.started = true
+	s.mu.Unlock()
+
+	listeners := make([]net.Listener, len(bindings))
+	for i, b := range bindings {
+		ln, e := b.listen()
+		if e != nil {
+			for _, l := range listeners[:i] {
+				l.Close()
+			}
+			s.markReady()
+			return e
+		}
+		listeners[i] = ln
+	}
+
+	return s.serve(ctx, bindings, listeners)
+}
+
+//Shutdown server gracefully, waits for connections until ctx done.
+// Server will not start if shut down before Ready
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	shutdown := s.shutdown
+	s.closed = true
+	s.mu.Unlock()
+
+	if shutdown == nil {
+		return nil
+	}
+
+	return shutdown(ctx)
+}
+
+//Ready closed when server is listening, or failed to start, Addr is nil if failed
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+//markReady close ready once, on serving or failing to start
+func (s *Server) markReady() {
+	s.readyOnce.Do(func() {
+		close(s.ready)
+	})
+}
+
+//Addr listening address of the first binding, nil before Ready
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.addrs) == 0 {
+		return nil
+	}
+
+	return s.addrs[0]
+}
+
+//Addrs listening addresses of bindings, nil before Ready
+func (s *Server) Addrs() []net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]net.Addr(nil), s.addrs...)
 }
 
-func (s *Server) serveFastHttp(addr string) {
-	srv := s.GetFastHttpServer(s.k)
+//WithConfig set config of server
+func (s *Server) WithConfig(c ServerConfig) *Server {
+	s.Config = c
+	return s
+}
+
+//Kernel of server, for registering routes before Start
+func (s *Server) Kernel() *Kernel {
+	return s.k
+}
 
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		ln, err := net.Listen("tcp", addr)
-		if err != nil {
-			log.Fatalf("listen %s error: %v", addr, err)
+func (s *Server) serve(ctx context.Context, bindings []Binding, listeners []net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		for _, ln := range listeners {
+			ln.Close()
 		}
-		if err = srv.Serve(ln); err != nil {
-			log.Fatalf("serve %s error: %v", addr, err)
+		s.markReady()
+		return nil
+	}
+
+	errc := make(chan error, len(listeners))
+	var shutdowns []func(ctx context.Context) error
+	for i, ln := range listeners {
+		k := bindings[i].Kernel
+		if k == nil {
+			k = s.k
 		}
-	}()
-	log.Printf("%s served at [%s]", logPrefix("fasthttp"), addr)
-	<-done
-	log.Printf("%s stoping...", logPrefix("fasthttp"))
-	if e := srv.Shutdown(); e != nil {
-		log.Fatalf("%s shutdown error: %v", logPrefix("fasthttp"), e)
-	}
-	log.Printf("%s stopped", logPrefix("fasthttp"))
-}
-
-func (s *Server) serveNetHttp(addr string) {
-	srv := nt.Server{
-		Addr:         addr,
-		Handler:      s.k,
-		ReadTimeout:  ReadTimeout,
-		WriteTimeout: WriteTimeout,
-	}
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != nt.ErrServerClosed {
-			log.Fatalf("listen %s error: %v", addr, err)
+		serve, shutdown := s.backend(k, ln, bindings[i].TLS)
+		shutdowns = append(shutdowns, shutdown)
+		s.addrs = append(s.addrs, ln.Addr())
+		s.listeners = append(s.listeners, ln)
+		s.bindAddrs = append(s.bindAddrs, bindings[i].key(ln))
+
+		go func(ln net.Listener) {
+			if e := serve(); e != nil {
+				errc <- fmt.Errorf("serve %s error: %w", ln.Addr(), e)
+				return
+			}
+			errc <- nil
+		}(ln)
+		log.Printf("%s served at [%s]", logPrefix(k.handlerName()), ln.Addr())
+	}
+	s.shutdown = onceShutdown(func(ctx context.Context) (err error) {
+		for _, shutdown := range shutdowns {
+			if e := shutdown(ctx); e != nil && err == nil {
+				err = e
+			}
 		}
-	}()
-	log.Printf("%s served at [%s]", logPrefix("net/http"), addr)
-	<-done
-	log.Printf("%s stoping...", logPrefix("net/http"))
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer func() {
-		// extra handling here
-		cancel()
-	}()
+		return
+	})
+	s.mu.Unlock()
+	s.markReady()
+	notifyReady()
+
+	// stopped by Shutdown, failed, or ctx done
+	var err error
+	remaining := len(listeners)
+	select {
+	case err = <-errc:
+		remaining--
+	case <-ctx.Done():
+		log.Printf("%s stoping...", logPrefix(s.k.handlerName()))
+	}
 
-	if e := srv.Shutdown(ctx); e != nil {
-		log.Fatalf("%s shutdown error: %v", logPrefix("net/http"), e)
+	sctx, cancel := context.WithTimeout(context.Background(), s.Config.resolve(s.k).ShutdownTimeout)
+	defer cancel()
+	if e := s.Shutdown(sctx); e != nil && err == nil {
+		err = fmt.Errorf("%s shutdown error: %w", logPrefix(s.k.handlerName()), e)
+	}
+	for ; remaining > 0; remaining-- {
+		if e := <-errc; e != nil && err == nil {
+			err = e
+		}
+	}
+	if ctx.Err() != nil {
+		log.Printf("%s stopped", logPrefix(s.k.handlerName()))
+	}
+
+	return err
+}
+
+//backend serve and shutdown of listener by handler of kernel
+func (s *Server) backend(k *Kernel, ln net.Listener, config *tls.Config) (serve func() error, shutdown func(ctx context.Context) error) {
+	if k.Handler == HandlerNetHttp {
+		srv := s.GetNetHttpServer(k)
+		serve = func() error {
+			var e error
+			if config != nil {
+				// "h2" is added to NextProtos by net/http
+				srv.TLSConfig = config.Clone()
+				e = srv.ServeTLS(ln, "", "")
+			} else {
+				e = srv.Serve(ln)
+			}
+			if e != nil && e != nt.ErrServerClosed {
+				return e
+			}
+			return nil
+		}
+
+		return serve, srv.Shutdown
+	}
+
+	srv := s.GetFastHttpServer(k)
+	if config != nil {
+		config = config.Clone()
+		if len(config.NextProtos) == 0 {
+			config.NextProtos = []string{"http/1.1"}
+		}
+		ln = tls.NewListener(ln, config)
+	}
+	serve = func() error {
+		return srv.Serve(ln)
+	}
+
+	return serve, srv.ShutdownWithContext
+}
+
+//onceShutdown shutdown called once, later calls wait for the first
+func onceShutdown(shutdown func(ctx context.Context) error) func(ctx context.Context) error {
+	var once sync.Once
+	done := make(chan struct{})
+	var err error
+
+	return func(ctx context.Context) error {
+		once.Do(func() {
+			err = shutdown(ctx)
+			close(done)
+		})
+		select {
+		case <-done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+//ShutdownOnSignal context canceled on SIGINT or SIGTERM
+func ShutdownOnSignal(parent context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(parent, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+}
+
+func (s *Server) GetNetHttpServer(kernel *Kernel) *nt.Server {
+	c := s.Config.resolve(kernel)
+	var handler nt.Handler = kernel
+	if c.MaxRequestBodySize > 0 {
+		handler = nt.MaxBytesHandler(handler, int64(c.MaxRequestBodySize))
+	}
+	if c.H2C {
+		// HTTP/2 without TLS, for internal traffic
+		handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: c.IdleTimeout})
+	}
+
+	return &nt.Server{
+		Handler:        handler,
+		ReadTimeout:    c.ReadTimeout,
+		WriteTimeout:   c.WriteTimeout,
+		IdleTimeout:    c.IdleTimeout,
+		MaxHeaderBytes: c.MaxHeaderBytes,
 	}
-	log.Printf("%s stopped", logPrefix("net/http"))
 }
 
 func (s *Server) GetFastHttpServer(kernel *Kernel) *fasthttp.Server {
+	c := s.Config.resolve(kernel)
+	readBufferSize := c.ReadBufferSize
+	if readBufferSize == 0 {
+		readBufferSize = c.MaxHeaderBytes
+	}
 
 	return &fasthttp.Server{
 		Handler:            kernel.FastHttpHandler,
-		Concurrency:        kernel.RequestCurrency,
-		TCPKeepalive:       kernel.IsKeepAlive(),
-		MaxRequestBodySize: kernel.MaxRequestBodySize,
-		ReadTimeout:        ReadTimeout,
-		WriteTimeout:       WriteTimeout,
-		IdleTimeout:        IdleTimeout,
+		Concurrency:        c.Concurrency,
+		TCPKeepalive:       c.TCPKeepalive,
+		MaxRequestBodySize: c.MaxRequestBodySize,
+		ReadTimeout:        c.ReadTimeout,
+		WriteTimeout:       c.WriteTimeout,
+		IdleTimeout:        c.IdleTimeout,
+		ReadBufferSize:     readBufferSize,
+		WriteBufferSize:    c.WriteBufferSize,
+		MaxConnsPerIP:      c.MaxConnsPerIP,
+		MaxRequestsPerConn: c.MaxRequestsPerConn,
+		ReduceMemoryUsage:  c.ReduceMemoryUsage,
 	}
 }
 
 func NewServer(cr ContainerRegister, debug bool) *Server {
 	k := NewKernel(cr, debug)
 
-	return &Server{k: k}
+	return &Server{k: k, ready: make(chan struct{})}
 }
 
 func logPrefix(handler string) string {
 	return fmt.Sprintf("enorith/%s (%s)", Version, handler)
 }
+
+func (k *Kernel) handlerName() string {
+	if k.Handler == HandlerNetHttp {
+		return "net/http"
+	}
+
+	return "fasthttp"
+}
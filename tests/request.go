@@ -10,9 +10,10 @@ import (
 
 type FakeRequest struct {
 	content.SimpleParamRequest
-	Path   string
-	Method string
-	Url    *url.URL
+	Path    string
+	Method  string
+	Url     *url.URL
+	Headers map[string]string
 }
 
 func (f FakeRequest) GetValue(key ...string) contracts.InputValue {
@@ -24,7 +25,7 @@ func (f FakeRequest) Context() context.Context {
 }
 
 func (f FakeRequest) Accepts() []byte {
-	panic("implement me")
+	return f.Header("Accept")
 }
 
 func (f FakeRequest) ExceptsJson() bool {
@@ -97,11 +98,11 @@ func (f FakeRequest) GetSignature() []byte {
 }
 
 func (f FakeRequest) Header(key string) []byte {
-	panic("implement me Header")
+	return []byte(f.HeaderString(key))
 }
 
 func (f FakeRequest) HeaderString(key string) string {
-	panic("implement me HeaderString")
+	return f.Headers[key]
 }
 
 func (f FakeRequest) SetHeader(key string, value []byte) contracts.RequestContract {
@@ -124,7 +125,19 @@ func (f FakeRequest) ToString() string {
 	return "fake request"
 }
 func (f FakeRequest) GetURL() *url.URL {
-	panic("not implemented") // TODO: Implement
+	if f.Url == nil {
+		return &url.URL{Path: f.Path}
+	}
+
+	return f.Url
+}
+
+func (f FakeRequest) Host() string {
+	if f.Url == nil {
+		return ""
+	}
+
+	return f.Url.Host
 }
 
 func NewRequest(method, path string) *FakeRequest {
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+//CertReloadInterval interval of checking certificate files for changes
+var CertReloadInterval = time.Second * 10
+
+//CertReloader certificate loaded from files, reloaded without dropping connections,
+// handshakes after reloading use the new certificate
+// 	cr, e := http.NewCertReloader("server.crt", "server.key")
+// 	go cr.Watch(ctx)
+// 	s.StartTLS(ctx, ":443", &tls.Config{GetCertificate: cr.GetCertificate})
+//
+type CertReloader struct {
+	certFile, keyFile string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+//GetCertificate current certificate, used as tls.Config.GetCertificate
+func (cr *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+
+	return cr.cert, nil
+}
+
+//Reload certificate from files, current certificate is kept on error
+func (cr *CertReloader) Reload() error {
+	cert, e := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if e != nil {
+		return fmt.Errorf("load certificate %s error: %w", cr.certFile, e)
+	}
+
+	cr.mu.Lock()
+	cr.cert = &cert
+	cr.modTime = cr.lastModified()
+	cr.mu.Unlock()
+
+	return nil
+}
+
+//Watch reload certificate on SIGHUP or files changed, until ctx done
+func (cr *CertReloader) Watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(CertReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-ticker.C:
+			cr.mu.RLock()
+			changed := cr.lastModified().After(cr.modTime)
+			cr.mu.RUnlock()
+			if !changed {
+				continue
+			}
+		}
+
+		if e := cr.Reload(); e != nil {
+			log.Printf("%s reload certificate error: %v", logPrefix("tls"), e)
+		}
+	}
+}
+
+//lastModified latest modification time of certificate and key files
+func (cr *CertReloader) lastModified() (t time.Time) {
+	for _, f := range []string{cr.certFile, cr.keyFile} {
+		if info, e := os.Stat(f); e == nil && info.ModTime().After(t) {
+			t = info.ModTime()
+		}
+	}
+
+	return
+}
+
+//NewCertReloader certificate reloader of files, certificate is loaded at once
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	cr := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if e := cr.Reload(); e != nil {
+		return nil, e
+	}
+
+	return cr, nil
+}
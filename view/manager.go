@@ -18,10 +18,23 @@ var DefaultManager *Manager
 type Manager struct {
 	fileSystem  fs.FS
 	ext, perfix string
+	funcs       template.FuncMap
+}
+
+//Funcs add template functions, eg: router.Wrapper.FuncMap()
+func (m *Manager) Funcs(funcs template.FuncMap) *Manager {
+	if m.funcs == nil {
+		m.funcs = make(template.FuncMap)
+	}
+	for k, v := range funcs {
+		m.funcs[k] = v
+	}
+
+	return m
 }
 
 func (m *Manager) Template(name string) (*template.Template, error) {
-	temp := template.New(name)
+	temp := template.New(name).Funcs(m.funcs)
 
 	b, e := m.Parse(name)
 